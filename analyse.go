@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/analyse"
+)
+
+// analyseExport reports the html elements, shortcodes, post metadata, templates, authors and link hosts used by the
+// visual posts and whether the converter handles them.
+func analyseExport(args []string) {
+	flags := flag.NewFlagSet("analyse", flag.ExitOnError)
+	loadConfig := configFlags(flags, "the config to load the visual export with")
+	outputFile := flags.String("out", "visual_export_analysis.csv", "the analysis csv to write")
+	flags.Parse(args)
+
+	_, plan := loadConfig()
+
+	if err := analyse.Analyse(plan).Write(*outputFile); err != nil {
+		exit(err)
+	}
+}
@@ -0,0 +1,239 @@
+package analyse
+
+import (
+	"encoding/csv"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/net/html"
+)
+
+const (
+	// the kinds of feature found in the visual posts
+	Element   = "element"
+	Attribute = "attribute"
+	Shortcode = "shortcode"
+	MetaKey   = "meta-key"
+	Template  = "template"
+	Author    = "author"
+	LinkHost  = "link-host"
+
+	templateMetaKey = "choose_post_template"
+	relativeHost    = "(relative)"
+	defaultTemplate = "(default)"
+	postSeparator   = "; "
+)
+
+var (
+	kinds = []string{Element, Attribute, Shortcode, MetaKey, Template, Author, LinkHost}
+
+	reportHeader = []string{"KIND", "NAME", "HANDLED", "OCCURRENCES", "POSTS", "UNHANDLED_POSTS"}
+
+	// the name of an opening shortcode, closing shortcodes are not counted
+	shortcodeNameRX = regexp.MustCompile(`^\[([a-z_]+)`)
+
+	// numbered and hashed meta keys e.g. more_information_3_url or _oembed_<md5> are counted together
+	metaKeyIndexRX = regexp.MustCompile(`_\d+(_|$)`)
+	metaKeyHashRX  = regexp.MustCompile(`_[0-9a-f]{32}$`)
+)
+
+// Feature an html element, attribute, shortcode, meta key, template, author or link host used by the visual posts.
+type Feature struct {
+	Kind        string
+	Name        string
+	Handled     bool
+	Occurrences int
+	Posts       []string
+	posts       map[string]bool
+}
+
+// Analysis the features used by every post in the visual export and whether the converter handles them.
+type Analysis struct {
+	plan     *migration.Plan
+	features map[string]*Feature
+}
+
+// Analyse walks every post in the visual export.
+func Analyse(plan *migration.Plan) *Analysis {
+	a := &Analysis{plan: plan, features: make(map[string]*Feature)}
+
+	urls := make([]string, 0, len(plan.VisualExport.Posts))
+	for u := range plan.VisualExport.Posts {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+
+	for _, u := range urls {
+		a.analysePost(u, plan.VisualExport.Posts[u])
+	}
+	return a
+}
+
+func (a *Analysis) analysePost(postURL string, i *gofeed.Item) {
+	content := ""
+	if encoded := i.Extensions["content"]["encoded"]; len(encoded) > 0 {
+		content = encoded[0].Value
+	}
+
+	z := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		t := z.Token()
+		a.add(Element, t.Data, postURL, zebedee.HandlesElement(t.Data))
+		for _, attr := range t.Attr {
+			a.add(Attribute, t.Data+" "+attr.Key, postURL, zebedee.HandlesAttribute(t.Data, attr.Key))
+
+			if (t.Data == "a" && attr.Key == "href") || ((t.Data == "img" || t.Data == "iframe") && attr.Key == "src") {
+				a.addLink(attr.Val, postURL)
+			}
+		}
+	}
+
+	for _, shortcode := range zebedee.UnresolvedShortcodes(content) {
+		if match := shortcodeNameRX.FindStringSubmatch(shortcode); match != nil {
+			a.add(Shortcode, match[1], postURL, zebedee.HandlesShortcode(match[1]))
+		}
+	}
+
+	template := defaultTemplate
+	for _, meta := range i.Extensions["wp"]["postmeta"] {
+		key := meta.Children["meta_key"][0].Value
+		a.add(MetaKey, normaliseMetaKey(key), postURL, zebedee.HandlesMetaKey(key))
+
+		if value := strings.TrimSpace(meta.Children["meta_value"][0].Value); key == templateMetaKey && !zebedee.HandlesTemplate(value) {
+			template = value
+		}
+	}
+	a.add(Template, template, postURL, template == defaultTemplate)
+
+	for _, author := range migration.GetCategories(i, migration.AuthorDomain) {
+		_, mapped := a.plan.Contacts.GetContact(author)
+		a.add(Author, author, postURL, mapped)
+	}
+
+	links, _, _ := zebedee.ParseMetadata(i)
+	for _, l := range links {
+		a.addLink(l.URI, postURL)
+	}
+}
+
+// addLink counts the host of the link. Links to other sites are kept and links to the visual site are rewritten, only
+// relative links cannot be migrated.
+func (a *Analysis) addLink(link string, postURL string) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		a.add(LinkHost, relativeHost, postURL, false)
+		return
+	}
+
+	switch {
+	case u.Host != "":
+		a.add(LinkHost, strings.ToLower(u.Host), postURL, true)
+	case u.Scheme != "":
+		a.add(LinkHost, u.Scheme+":", postURL, true)
+	case strings.HasPrefix(u.Path, "#") || u.Fragment != "" && u.Path == "":
+		a.add(LinkHost, "#", postURL, true)
+	default:
+		a.add(LinkHost, relativeHost, postURL, false)
+	}
+}
+
+func (a *Analysis) add(kind string, name string, postURL string, handled bool) {
+	key := kind + " " + name
+	f, ok := a.features[key]
+	if !ok {
+		f = &Feature{Kind: kind, Name: name, Handled: handled, posts: make(map[string]bool)}
+		a.features[key] = f
+	}
+
+	f.Occurrences++
+	if !f.posts[postURL] {
+		f.posts[postURL] = true
+		f.Posts = append(f.Posts, postURL)
+	}
+}
+
+// Features returns the features grouped by kind, unhandled features first then the most used.
+func (a *Analysis) Features() []*Feature {
+	order := make(map[string]int)
+	for i, k := range kinds {
+		order[k] = i
+	}
+
+	features := make([]*Feature, 0, len(a.features))
+	for _, f := range a.features {
+		features = append(features, f)
+	}
+
+	sort.Slice(features, func(i, j int) bool {
+		fi, fj := features[i], features[j]
+		switch {
+		case fi.Kind != fj.Kind:
+			return order[fi.Kind] < order[fj.Kind]
+		case fi.Handled != fj.Handled:
+			return !fi.Handled
+		case len(fi.Posts) != len(fj.Posts):
+			return len(fi.Posts) > len(fj.Posts)
+		default:
+			return fi.Name < fj.Name
+		}
+	})
+	return features
+}
+
+// Write writes the report to a csv file, listing the posts that use each unhandled feature.
+func (a *Analysis) Write(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return migration.Error{Message: "failed to create analysis file", OriginalErr: err, Params: log.Data{"path": filename}}
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	defer w.Flush()
+	w.Write(reportHeader)
+
+	unhandled := make(map[string]int)
+	for _, feature := range a.Features() {
+		posts := ""
+		if !feature.Handled {
+			posts = strings.Join(feature.Posts, postSeparator)
+			unhandled[feature.Kind]++
+		}
+		w.Write([]string{
+			feature.Kind,
+			feature.Name,
+			strconv.FormatBool(feature.Handled),
+			strconv.Itoa(feature.Occurrences),
+			strconv.Itoa(len(feature.Posts)),
+			posts,
+		})
+	}
+
+	data := log.Data{"output": filename}
+	for kind, count := range unhandled {
+		data["unhandled_"+kind] = count
+	}
+	log.Info("visual export analysis complete", data)
+	return nil
+}
+
+func normaliseMetaKey(key string) string {
+	key = metaKeyHashRX.ReplaceAllString(key, "_<hash>")
+	return metaKeyIndexRX.ReplaceAllString(key, "_N$1")
+}
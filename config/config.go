@@ -1,28 +1,313 @@
 package config
 
 import (
-	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// the maximum number of keywords on an ONS page
+	defaultMaxKeywords = 10
+
+	// ProfileEnv the environment variable naming the profile to use if none is given on the command line.
+	ProfileEnv = "VISUAL_MIGRATION_PROFILE"
 )
 
 type Model struct {
+	// Profile the named section of the config file applied over the top level settings e.g. local, sandbox or prod.
+	Profile string `yaml:"profile,omitempty"`
+
 	MappingFile         string `yaml:"migration-file"`
 	VisualExportFile    string `yaml:"visual-rss-file"`
 	CollectionsDir      string `yaml:"collections-dir"`
 	NationalArchivesURL string `yaml:"national-archives-url"`
-	ResultsFilePath     string `yaml:"results-file-path"`
+
+	// NationalArchivesCDXFile a CDX index of the web archive captures of visual.ons.gov.uk, the capture of each url at
+	// or before NationalArchivesCutover (UK time, defaults to now) is used instead of the NationalArchivesURL snapshot.
+	NationalArchivesCDXFile string `yaml:"national-archives-cdx-file"`
+	NationalArchivesCutover string `yaml:"national-archives-cutover"`
+
+	ResultsFilePath string `yaml:"results-file-path"`
+
+	// PublishedContentDir a local copy of the published ONS content. Articles migrated to a URI that is already
+	// published are written as a new version of the published page, with the VersionNotice as its correction notice.
+	PublishedContentDir string `yaml:"published-content-dir"`
+	VersionNotice       string `yaml:"version-notice"`
+
+	// CollectionGrouping how migrated articles are placed into collections: row, taxonomy, column or size.
+	CollectionGrouping     string `yaml:"collection-grouping"`
+	CollectionGroupColumn  string `yaml:"collection-group-column"`
+	CollectionGroupSize    int    `yaml:"collection-group-size"`
+	CollectionNameTemplate string `yaml:"collection-name-template"`
+
+	// CollectionType manual or scheduled, scheduled collections publish on PublishDate or the date in PublishDateColumn.
+	CollectionType    string `yaml:"collection-type"`
+	PublishDate       string `yaml:"publish-date"`
+	PublishDateColumn string `yaml:"publish-date-column"`
+
+	// ReleaseDateSource the visual post date used as the article release date: published (default) or modified.
+	ReleaseDateSource string `yaml:"release-date-source"`
+
+	// DeterministicIDs generate collection IDs from the CollectionIDNamespace and visual URLs instead of at random.
+	DeterministicIDs      bool   `yaml:"deterministic-collection-ids"`
+	CollectionIDNamespace string `yaml:"collection-id-namespace"`
+
+	// RunTime the time recorded in collection histories and as the update date of versions of published pages, an RFC
+	// 3339 timestamp - now if it is not set. Deterministic runs must set it to reproduce the same collections.
+	RunTime string `yaml:"run-time"`
+
+	// ContactsFile the wordpress author to ONS contact details lookup.
+	ContactsFile string    `yaml:"contacts-file"`
+	Contacts     *Contacts `yaml:"-"`
+
+	// VocabularyFile the controlled vocabulary mapping wordpress tags and categories to ONS keywords and topics.
+	VocabularyFile string      `yaml:"vocabulary-file"`
+	Vocabulary     *Vocabulary `yaml:"-"`
+
+	// PageType the ONS page type posts are migrated to, unless the mapping has a value in the PageTypeColumn:
+	// article (default), static_page, static_article or compendium_chapter. Compendium chapters name their compendium
+	// in the CompendiumColumn.
+	PageType         string `yaml:"page-type"`
+	PageTypeColumn   string `yaml:"page-type-column"`
+	CompendiumColumn string `yaml:"compendium-column"`
+
+	// SchemaDir the directory of the JSON Schemas pages are validated against before they are written, one for each
+	// page type named <type>.schema.json.
+	SchemaDir string `yaml:"schema-dir"`
+
+	// WelshExportFile a wordpress export of the Welsh visual site and WelshTranslationsDir a directory of <post id>.yml
+	// translations, either matched to the English posts by post id. Translated posts are written as data_cy.json
+	// alongside the data.json of the English page.
+	WelshExportFile      string `yaml:"welsh-rss-file"`
+	WelshTranslationsDir string `yaml:"welsh-translations-dir"`
+
+	// ReviewStage the review stage migrated content is moved to once every row of its collection has migrated:
+	// inprogress (default), complete or reviewed. Content is completed by CompletedBy and reviewed by ReviewedBy, the
+	// emails of the zebedee users recorded in the collection events.
+	ReviewStage string `yaml:"review-stage"`
+	CompletedBy string `yaml:"completed-by"`
+	ReviewedBy  string `yaml:"reviewed-by"`
+
+	Sites `yaml:",inline"`
+}
+
+// Sites the hosts and paths of the visual site the content is migrated from and the ONS sites it is migrated to.
+type Sites struct {
+	VisualHost        string `yaml:"visual-host"`
+	VisualUploadsPath string `yaml:"visual-uploads-path"`
+	ONSSite           string `yaml:"ons-site"`
+	StaticONSSite     string `yaml:"static-ons-site"`
+	StaticONSPath     string `yaml:"static-ons-path"`
+}
+
+// IsONSHost returns true if the host is that of the ONS site, with or without www.
+func (s Sites) IsONSHost(host string) bool {
+	return sameHost(host, s.ONSSite)
+}
+
+// IsStaticONSHost returns true if the host is that of the static ONS site, with or without www.
+func (s Sites) IsStaticONSHost(host string) bool {
+	return sameHost(host, s.StaticONSSite)
+}
+
+func sameHost(host string, site string) bool {
+	u, err := url.Parse(site)
+	if err != nil {
+		return false
+	}
+	return strings.TrimPrefix(strings.ToLower(host), "www.") == strings.TrimPrefix(strings.ToLower(u.Host), "www.")
 }
 
-func Load(filename string) (*Model, error) {
+// file the config file, the top level settings and the named profiles that override them.
+type file struct {
+	Model    `yaml:",inline"`
+	Profiles map[string]yaml.MapSlice `yaml:"profiles"`
+}
+
+func defaults() Model {
+	return Model{
+		SchemaDir: "resources/schemas",
+		Sites: Sites{
+			VisualHost:        "visual.ons.gov.uk",
+			VisualUploadsPath: "/wp-content/uploads/",
+			ONSSite:           "https://www.ons.gov.uk",
+			StaticONSSite:     "https://static.ons.gov.uk",
+			StaticONSPath:     "/visual/",
+		},
+	}
+}
+
+// Contact the contact details published on an ONS article.
+type Contact struct {
+	Name      string `yaml:"name"`
+	Email     string `yaml:"email"`
+	Telephone string `yaml:"telephone"`
+}
+
+// Vocabulary maps mapping keywords and wordpress tags to ONS keywords and wordpress categories to ONS taxonomy topic
+// URIs. A keyword mapped to an empty string is dropped. If Strict is true wordpress tags without an entry are dropped,
+// otherwise they are used as they are.
+type Vocabulary struct {
+	MaxKeywords int               `yaml:"max-keywords"`
+	Strict      bool              `yaml:"strict"`
+	Keywords    map[string]string `yaml:"keywords"`
+	Topics      map[string]string `yaml:"topics"`
+}
+
+// Contacts the contact details for each wordpress author, keyed by author login. TeamMailbox is used for authors
+// without an entry and to fill in any details - name, email or telephone - missing from an author's entry.
+type Contacts struct {
+	TeamMailbox *Contact            `yaml:"team-mailbox"`
+	Authors     map[string]*Contact `yaml:"authors"`
+}
+
+// Load builds the config in layers, each overriding the one before: the defaults, the top level settings of the config
+// file, the profile section of the file, VISUAL_MIGRATION_* environment variables and lastly the overrides given on the
+// command line. If profile is empty the ProfileEnv environment variable is used. The result is validated and every
+// problem found is reported at once.
+func Load(filename string, profile string, overrides Overrides) (*Model, error) {
 	source, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	var cfg Model
-	if err := yaml.Unmarshal(source, &cfg); err != nil {
+	f := file{Model: defaults()}
+	if err := yaml.UnmarshalStrict(source, &f); err != nil {
+		return nil, errors.Wrapf(err, "invalid config file %s", filename)
+	}
+	cfg := f.Model
+
+	if profile == "" {
+		profile = os.Getenv(ProfileEnv)
+	}
+	if profile != "" {
+		settings, ok := f.Profiles[profile]
+		if !ok {
+			return nil, errors.Errorf("profile %q is not defined in config file %s", profile, filename)
+		}
+
+		b, err := yaml.Marshal(settings)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.UnmarshalStrict(b, &cfg); err != nil {
+			return nil, errors.Wrapf(err, "invalid profile %q in config file %s", profile, filename)
+		}
+		cfg.Profile = profile
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.apply(overrides); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 
+	cfg.Contacts = &Contacts{Authors: map[string]*Contact{}}
+	if cfg.ContactsFile != "" {
+		if cfg.Contacts, err = loadContacts(cfg.ContactsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg.Vocabulary = &Vocabulary{MaxKeywords: defaultMaxKeywords, Keywords: map[string]string{}, Topics: map[string]string{}}
+	if cfg.VocabularyFile != "" {
+		if cfg.Vocabulary, err = loadVocabulary(cfg.VocabularyFile); err != nil {
+			return nil, err
+		}
+	}
+
 	return &cfg, nil
 }
+
+func loadVocabulary(filename string) (*Vocabulary, error) {
+	source, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	vocab := Vocabulary{MaxKeywords: defaultMaxKeywords}
+	if err := yaml.Unmarshal(source, &vocab); err != nil {
+		return nil, err
+	}
+
+	// terms are matched case insensitively
+	keywords := make(map[string]string)
+	for term, keyword := range vocab.Keywords {
+		keywords[strings.ToLower(strings.TrimSpace(term))] = strings.TrimSpace(keyword)
+	}
+	vocab.Keywords = keywords
+
+	topics := make(map[string]string)
+	for category, uri := range vocab.Topics {
+		topics[strings.ToLower(strings.TrimSpace(category))] = strings.TrimSpace(uri)
+	}
+	vocab.Topics = topics
+
+	return &vocab, nil
+}
+
+// GetKeyword returns the ONS keyword for the term, false if the term has no entry in the vocabulary.
+func (v *Vocabulary) GetKeyword(term string) (string, bool) {
+	keyword, ok := v.Keywords[strings.ToLower(strings.TrimSpace(term))]
+	return keyword, ok
+}
+
+// GetTopic returns the taxonomy topic URI for the wordpress category, false if the category has no topic.
+func (v *Vocabulary) GetTopic(category string) (string, bool) {
+	uri, ok := v.Topics[strings.ToLower(strings.TrimSpace(category))]
+	return uri, ok && uri != ""
+}
+
+func loadContacts(filename string) (*Contacts, error) {
+	source, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var contacts Contacts
+	if err := yaml.Unmarshal(source, &contacts); err != nil {
+		return nil, err
+	}
+
+	// author logins are matched case insensitively
+	authors := make(map[string]*Contact)
+	for login, c := range contacts.Authors {
+		authors[strings.ToLower(login)] = c
+	}
+	contacts.Authors = authors
+
+	return &contacts, nil
+}
+
+// GetContact returns the contact details for the wordpress author, false if the author has no entry and the team
+// mailbox has been used instead. Returns nil if there are no contact details for the author.
+func (c *Contacts) GetContact(author string) (*Contact, bool) {
+	contact, ok := c.Authors[strings.ToLower(author)]
+	if !ok {
+		return c.TeamMailbox, false
+	}
+
+	if c.TeamMailbox != nil {
+		merged := *contact
+		if merged.Name == "" {
+			merged.Name = c.TeamMailbox.Name
+		}
+		if merged.Email == "" {
+			merged.Email = c.TeamMailbox.Email
+		}
+		if merged.Telephone == "" {
+			merged.Telephone = c.TeamMailbox.Telephone
+		}
+		contact = &merged
+	}
+	return contact, true
+}
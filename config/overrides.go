@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const envPrefix = "VISUAL_MIGRATION_"
+
+// Overrides config settings given on the command line as key=value using the config file keys e.g.
+// -set collections-dir=/tmp/collections. It implements flag.Value so the flag can be repeated.
+type Overrides []string
+
+func (o *Overrides) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *Overrides) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return errors.Errorf("expected key=value but was %q", value)
+	}
+	*o = append(*o, value)
+	return nil
+}
+
+// EnvName returns the environment variable overriding the config key e.g. collections-dir is overridden by
+// VISUAL_MIGRATION_COLLECTIONS_DIR.
+func EnvName(key string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(key, "-", "_", -1))
+}
+
+// Keys returns the keys of every setting that can be overridden.
+func Keys() []string {
+	keys := make([]string, 0)
+	for key := range settings(&Model{}) {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (cfg *Model) applyEnv() error {
+	fields := settings(cfg)
+	for _, key := range Keys() {
+		if value, ok := os.LookupEnv(EnvName(key)); ok {
+			if err := set(fields[key], value); err != nil {
+				return errors.Wrapf(err, "invalid value for environment variable %s", EnvName(key))
+			}
+		}
+	}
+	return nil
+}
+
+func (cfg *Model) apply(overrides Overrides) error {
+	fields := settings(cfg)
+	for _, o := range overrides {
+		kv := strings.SplitN(o, "=", 2)
+		field, ok := fields[kv[0]]
+		if !ok {
+			return errors.Errorf("unknown config setting %q, expected one of %s", kv[0], strings.Join(Keys(), ", "))
+		}
+		if err := set(field, kv[1]); err != nil {
+			return errors.Wrapf(err, "invalid value for config setting %s", kv[0])
+		}
+	}
+	return nil
+}
+
+// settings returns the string, int and bool fields of the config keyed by their config file key, including those of
+// inlined structs.
+func settings(cfg *Model) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			tag := strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")
+			switch {
+			case len(tag) > 1 && tag[1] == "inline" && v.Field(i).Kind() == reflect.Struct:
+				walk(v.Field(i))
+			case tag[0] == "" || tag[0] == "-" || tag[0] == "profile":
+			case v.Field(i).Kind() == reflect.String, v.Field(i).Kind() == reflect.Int, v.Field(i).Kind() == reflect.Bool:
+				fields[tag[0]] = v.Field(i)
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem())
+	return fields
+}
+
+func set(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		field.SetString(value)
+	}
+	return nil
+}
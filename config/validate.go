@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ValidationError every problem found with the config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("invalid config, %d problem(s):\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// Validate checks the files and directories the config refers to exist and its URLs are well formed, returning a
+// ValidationError listing every problem found.
+func (cfg *Model) Validate() error {
+	v := &validator{}
+
+	v.file("migration-file", cfg.MappingFile, true)
+	v.file("visual-rss-file", cfg.VisualExportFile, true)
+	v.file("contacts-file", cfg.ContactsFile, false)
+	v.file("vocabulary-file", cfg.VocabularyFile, false)
+	v.file("national-archives-cdx-file", cfg.NationalArchivesCDXFile, false)
+	v.dir("schema-dir", cfg.SchemaDir, true)
+	v.file("welsh-rss-file", cfg.WelshExportFile, false)
+	v.dir("welsh-translations-dir", cfg.WelshTranslationsDir, false)
+
+	v.dir("published-content-dir", cfg.PublishedContentDir, false)
+
+	v.url("national-archives-url", cfg.NationalArchivesURL)
+	v.site("ons-site", cfg.ONSSite)
+	v.site("static-ons-site", cfg.StaticONSSite)
+
+	if cfg.VisualHost == "" || strings.ContainsAny(cfg.VisualHost, ":/") {
+		v.add(fmt.Sprintf("visual-host must be a host name e.g. visual.ons.gov.uk but was %q", cfg.VisualHost))
+	}
+	v.path("visual-uploads-path", cfg.VisualUploadsPath)
+	v.path("static-ons-path", cfg.StaticONSPath)
+
+	switch cfg.ReleaseDateSource {
+	case "", "published", "modified":
+	default:
+		v.add(fmt.Sprintf("release-date-source must be published or modified but was %q", cfg.ReleaseDateSource))
+	}
+
+	switch cfg.ReviewStage {
+	case "", "inprogress":
+	case "complete", "reviewed":
+		v.reviewer("completed-by", cfg.CompletedBy)
+		if cfg.ReviewStage == "reviewed" {
+			v.reviewer("reviewed-by", cfg.ReviewedBy)
+		}
+	default:
+		v.add(fmt.Sprintf("review-stage must be inprogress, complete or reviewed but was %q", cfg.ReviewStage))
+	}
+	if cfg.CompletedBy != "" && strings.EqualFold(cfg.CompletedBy, cfg.ReviewedBy) {
+		v.add("completed-by and reviewed-by must be different users, zebedee does not allow content to be reviewed by the user who completed it")
+	}
+
+	if cfg.DeterministicIDs && cfg.CollectionIDNamespace == "" {
+		v.add("collection-id-namespace is required when deterministic-collection-ids is true")
+	}
+	if cfg.RunTime != "" {
+		if _, err := time.Parse(time.RFC3339, cfg.RunTime); err != nil {
+			v.add(fmt.Sprintf("run-time must be an RFC 3339 timestamp e.g. 2018-02-01T09:30:00Z but was %q", cfg.RunTime))
+		}
+	} else if cfg.DeterministicIDs {
+		v.add("run-time is required when deterministic-collection-ids is true")
+	}
+
+	if len(v.problems) > 0 {
+		return ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// ValidateResultsPath checks the directory of results-file-path exists, returning a ValidationError if not. It is only
+// checked by the commands that write results to it.
+func (cfg *Model) ValidateResultsPath() error {
+	v := &validator{}
+	if cfg.ResultsFilePath == "" {
+		v.add("results-file-path is required")
+	} else {
+		v.dir("results-file-path directory", filepath.Dir(cfg.ResultsFilePath), true)
+	}
+
+	if len(v.problems) > 0 {
+		return ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// ValidateCollectionsDir checks collections-dir exists, returning a ValidationError if not. It is only checked by the
+// commands that write collections to it.
+func (cfg *Model) ValidateCollectionsDir() error {
+	v := &validator{}
+	v.dir("collections-dir", cfg.CollectionsDir, true)
+
+	if len(v.problems) > 0 {
+		return ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(problem string) {
+	v.problems = append(v.problems, problem)
+}
+
+func (v *validator) file(key string, path string, required bool) {
+	v.stat(key, path, required, false)
+}
+
+func (v *validator) dir(key string, path string, required bool) {
+	v.stat(key, path, required, true)
+}
+
+func (v *validator) stat(key string, path string, required bool, dir bool) {
+	if path == "" {
+		if required {
+			v.add(key + " is required")
+		}
+		return
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		v.add(fmt.Sprintf("%s %s does not exist", key, path))
+	case err != nil:
+		v.add(fmt.Sprintf("%s %s: %s", key, path, err))
+	case dir && !info.IsDir():
+		v.add(fmt.Sprintf("%s %s is not a directory", key, path))
+	case !dir && info.IsDir():
+		v.add(fmt.Sprintf("%s %s is a directory", key, path))
+	}
+}
+
+func (v *validator) url(key string, raw string) {
+	if raw == "" {
+		v.add(key + " is required")
+		return
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add(fmt.Sprintf("%s must be an absolute http or https url but was %q", key, raw))
+	}
+}
+
+// site checks the url is the root of a site, paths are appended to it.
+func (v *validator) site(key string, raw string) {
+	v.url(key, raw)
+	if strings.HasSuffix(raw, "/") {
+		v.add(fmt.Sprintf("%s must not end with / but was %q", key, raw))
+	}
+}
+
+// reviewer checks the email of a zebedee user is given.
+func (v *validator) reviewer(key string, email string) {
+	if !strings.Contains(email, "@") {
+		v.add(fmt.Sprintf("%s must be the email of a zebedee user but was %q", key, email))
+	}
+}
+
+func (v *validator) path(key string, path string) {
+	if !strings.HasPrefix(path, "/") || !strings.HasSuffix(path, "/") {
+		v.add(fmt.Sprintf("%s must start and end with / but was %q", key, path))
+	}
+}
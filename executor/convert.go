@@ -0,0 +1,64 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/mmcdole/gofeed"
+)
+
+// Convert runs the migration of a single mapping entry in memory, returning the converted article and the visual post
+// it was converted from. If the conversion fails the article is returned, if it was created, so its URI and warnings
+// can be reported.
+func Convert(plan *migration.Plan, article *migration.Article) (*zebedee.Article, *gofeed.Item, error) {
+	if err := article.Valid(); err != nil {
+		return nil, nil, err
+	}
+
+	visualItem, ok := plan.VisualExport.GetPost(article.VisualURL)
+	if !ok {
+		return nil, nil, migration.Error{Message: entryNotFound, OriginalErr: nil, Params: log.Data{"visualURL": article.VisualURL}}
+	}
+
+	a, err := zebedee.CreateArticle(plan, article, visualItem)
+	if err != nil {
+		return nil, visualItem, err
+	}
+
+	if err := a.ConvertToONSFormat(plan); err != nil {
+		return a, visualItem, migration.Error{Message: conversionErr, OriginalErr: err, Params: log.Data{"title": visualItem.Title}}
+	}
+
+	if err := zebedee.CheckPublished(a); err != nil {
+		return a, visualItem, err
+	}
+	return a, visualItem, nil
+}
+
+// ConvertWelsh converts the Welsh translation of the visual post to the Welsh version of the converted English article.
+// If the plan has Welsh translations but none for the post a warning is added to the English article and nil returned.
+// Warnings from converting the translation are added to the English article, prefixed welsh.
+func ConvertWelsh(plan *migration.Plan, a *zebedee.Article, visualItem *gofeed.Item) (*zebedee.Article, error) {
+	if plan.Welsh == nil || a == nil || visualItem == nil {
+		return nil, nil
+	}
+
+	postID := migration.GetPostID(visualItem)
+	translation, ok := plan.Welsh.Get(postID)
+	if !ok {
+		a.Warnings = append(a.Warnings, fmt.Sprintf("%s %s", zebedee.NoWelshTranslationWarning, postID))
+		return nil, nil
+	}
+
+	cy := zebedee.CreateWelshArticle(a, translation)
+	err := cy.ConvertToONSFormat(plan)
+	for _, w := range cy.Warnings {
+		a.Warnings = append(a.Warnings, welshWarningPrefix+w)
+	}
+	if err != nil {
+		return nil, migration.Error{Message: welshConversionErr, OriginalErr: err, Params: log.Data{"postID": postID, "source": translation.Source}}
+	}
+	return cy, nil
+}
@@ -5,35 +5,62 @@ import (
 	"github.com/ONSdigital/dp-visual-ons-migration/migration"
 	"encoding/csv"
 	"strconv"
-	"github.com/mmcdole/gofeed"
 	"github.com/ONSdigital/go-ns/log"
 	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"time"
+	"strings"
+	"encoding/json"
+	"github.com/ONSdigital/dp-visual-ons-migration/config"
+	"sort"
+	"sync/atomic"
 )
 
 const (
 	entryNotFound = "visual url entry was not found in this version of the wordpress export mapping"
 	conversionErr = "error while attempting to convert visual post to collection article"
+
+	welshConversionErr = "error while attempting to convert welsh translation of visual post"
+	welshWarningPrefix = "welsh: "
 )
 
 var (
-	resultsFileHeader = []string{"MAPPING_ROW_INDEX", "COLLECTION_NAME", "STATUS", "VISUAL_URL", "ONS_URL", "ERROR_DETAILS"}
+	resultsFileHeader = []string{"MAPPING_ROW_INDEX", "COLLECTION_NAME", "STATUS", "VISUAL_URL", "ONS_URL", "ERROR_DETAILS", "WARNINGS"}
 )
 
 type Executor struct {
-	plan            *migration.Plan
-	startIndex      int
-	currentRowIndex int
+	cfg           *config.Model
+	plan          *migration.Plan
+	grouping      *Grouping
+	schedule      *Schedule
+	collections   map[string]*zebedee.Collection
+	collectionErr map[string]error
+	// the rows of each author without contact details and the rows of posts without a welsh translation
+	unmappedAuthors map[string][]int
+	untranslated    []int
 	errorsCount     int
 	errFile         *os.File
 	resultsFile     *os.File
 	errWriter       *csv.Writer
 	resultsWriter   *csv.Writer
+	jsonFile        *os.File
+	jsonEncoder     *json.Encoder
+	// the outcome of every row and the run details for the report
+	results []*Result
+	inputs  []inputFile
+	started time.Time
+	// the number of results written to the json results file, they are written once their files are in place
+	jsonWritten int
+	// set by Stop, no more rows are migrated once it is
+	stopped int32
 }
 
 func newFile(name string) (*os.File, error) {
 	var f *os.File
 	if _, err := os.Stat(name); os.IsNotExist(err) {
-		f, _ = os.Create(name)
+		f, err = os.Create(name)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		os.Remove(name)
 		f, err = os.Create(name)
@@ -44,102 +71,287 @@ func newFile(name string) (*os.File, error) {
 	return f, nil
 }
 
-func New(plan *migration.Plan, startIndex int, resultsPath string) (*Executor, error) {
-	resultsFile, _ := newFile(resultsPath)
+func New(cfg *config.Model, plan *migration.Plan, grouping *Grouping, schedule *Schedule, resultsPath string) (*Executor, error) {
+	resultsFile, err := newFile(resultsPath)
+	if err != nil {
+		return nil, migration.Error{Message: "failed to create results file", OriginalErr: err, Params: log.Data{"path": resultsPath}}
+	}
 	resultsWriter := csv.NewWriter(resultsFile)
 	resultsWriter.Write(resultsFileHeader)
 
-	return &Executor{plan: plan,
-		errorsCount: 0,
-		currentRowIndex: startIndex,
-		resultsFile: resultsFile,
-		resultsWriter: resultsWriter,
+	jsonPath := JSONResultsPath(resultsPath)
+	jsonFile, err := newFile(jsonPath)
+	if err != nil {
+		return nil, migration.Error{Message: "failed to create json results file", OriginalErr: err, Params: log.Data{"path": jsonPath}}
+	}
+
+	return &Executor{cfg: cfg,
+		plan:            plan,
+		grouping:        grouping,
+		schedule:        schedule,
+		collections:     make(map[string]*zebedee.Collection),
+		collectionErr:   make(map[string]error),
+		unmappedAuthors: make(map[string][]int),
+		errorsCount:     0,
+		resultsFile:     resultsFile,
+		resultsWriter:   resultsWriter,
+		jsonFile:        jsonFile,
+		jsonEncoder:     json.NewEncoder(jsonFile),
+		results:         make([]*Result, 0),
+		inputs:          checksumInputs(cfg),
+		started:         time.Now(),
 	}, nil
 }
 
-func (e *Executor) Migrate(start int, batchSize int) {
-	end := start + batchSize
-
-	if end > len(e.plan.Mapping.ToMigrate) {
-		log.Debug("batch size exceeds input total input length, reducing batch size", log.Data{
-			"original": batchSize,
-			"new":      len(e.plan.Mapping.ToMigrate),
-		})
-		batchSize = len(e.plan.Mapping.ToMigrate)
+// Migrate migrates the mapping entries, grouping them into collections.
+func (e *Executor) Migrate(batch []*migration.Article) {
+	if len(batch) == 0 {
+		log.Info("no mapping entries to process", nil)
+		return
 	}
+	log.Info("processing batch", log.Data{"rows": len(batch), "first": batch[0].Row, "last": batch[len(batch)-1].Row})
 
-	// use the list to maintain the order in which the entries appear in the file
-	log.Info("processing batch", log.Data{"start": start, "end": end})
+	collectionNames, err := e.grouping.Assign(batch)
+	if err != nil {
+		for _, article := range batch {
+			e.logMigrationOutcome(&Result{Row: article.Row, VisualURL: article.VisualURL}, err)
+		}
+		return
+	}
 
-	batch := e.plan.Mapping.ToMigrate[start:end]
+	e.migrateBatch(batch, collectionNames)
+}
 
+// migrateBatch migrates each mapping entry into the collection named for its row.
+func (e *Executor) migrateBatch(batch []*migration.Article, collectionNames map[int]string) {
+	// the visual urls in each collection identify its content when generating deterministic collection IDs
+	collectionURLs := make(map[string][]string)
 	for _, article := range batch {
+		name := collectionNames[article.Row]
+		collectionURLs[name] = append(collectionURLs[name], article.VisualURL)
+	}
 
-		if err := article.Valid(); err != nil {
-			e.logMigrationOutcome(err, article.VisualURL, "", "")
-			continue
+	failed := make(map[string]bool)
+	for i, article := range batch {
+		if e.isStopped() {
+			// the collections of the rows not migrated are incomplete, so they are left in progress
+			for _, skipped := range batch[i:] {
+				failed[collectionNames[skipped.Row]] = true
+			}
+			log.Info("run stopped, the remaining rows were not migrated", log.Data{"rows": len(batch) - i, "first": article.Row})
+			break
 		}
 
-		var visualItem *gofeed.Item
-		var ok bool
+		r := &Result{Row: article.Row, CollectionName: collectionNames[article.Row], VisualURL: article.VisualURL}
+		err := e.migrateArticle(article, r, collectionURLs[r.CollectionName])
+		e.logMigrationOutcome(r, err)
+		failed[r.CollectionName] = failed[r.CollectionName] || err != nil
+	}
 
-		if visualItem, ok = e.plan.VisualExport.Posts[article.VisualURL]; !ok {
-			err := migration.Error{Message: entryNotFound, OriginalErr: nil, Params: log.Data{"visualURL": article.VisualURL}}
-			e.logMigrationOutcome(err, article.VisualURL, "", "")
-			continue
-		}
+	// the json results record the paths of the files of each row, so they are written once promote has moved them
+	e.promote(failed)
+	e.writeJSONResults()
+}
 
-		// offset the index to align with the input mapping - the header row is ignored and array is indexed from 0
-		collectionName := zebedee.ToCollectionName(e.currentRowIndex+2, article.PostTitle)
+// Stop stops the migration after the row in progress, the rows not yet migrated are skipped. It is safe to call from
+// another goroutine, the executor must still be closed to write the results.
+func (e *Executor) Stop() {
+	atomic.StoreInt32(&e.stopped, 1)
+}
 
-		col, err := zebedee.CreateCollection(collectionName)
-		if err != nil {
-			e.logMigrationOutcome(err, article.VisualURL, "", collectionName)
+func (e *Executor) isStopped() bool {
+	return atomic.LoadInt32(&e.stopped) == 1
+}
+
+// promote moves the content of the collections of the batch to the configured review stage. A collection with a row
+// that failed to migrate is left in progress for publishing support to fix.
+func (e *Executor) promote(failed map[string]bool) {
+	if zebedee.ReviewStageIndex(e.cfg.ReviewStage) <= 0 {
+		return
+	}
+
+	names := make([]string, 0, len(failed))
+	for name := range failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		col, ok := e.collections[name]
+		if !ok {
 			continue
 		}
-
-		a := zebedee.CreateArticle(article, visualItem)
-		if err := a.ConvertToONSFormat(e.plan); err != nil {
-			err := migration.Error{Message: conversionErr, OriginalErr: err, Params: log.Data{"title": visualItem.Title}}
-			e.logMigrationOutcome(err, article.VisualURL, a.URI, collectionName)
+		if failed[name] {
+			log.Info("collection has rows that failed to migrate, leaving it in progress", log.Data{"collection": name})
 			continue
 		}
 
-/*		if uri := e.plan.VisualExport.GetThumbnailURL(a.ImageURI); uri != nil {
-			e.plan.GetMigratedURL(uri.String())
-			imgURI, err := e.plan.GetMigratedURL(uri.String())
-			if err != nil {
-				e.logMigrationOutcome(err, article.VisualURL, a.URI, collectionName)
+		if err := col.Promote(e.cfg.ReviewStage, zebedee.Now()); err != nil {
+			log.ErrorC("failed to move collection to review stage", err, log.Data{"collection": name, "stage": e.cfg.ReviewStage})
+			continue
+		}
+		for _, r := range e.results {
+			if r.CollectionName != name {
 				continue
 			}
-			a.ImageURI = imgURI
-		}*/
+			for i, f := range r.Files {
+				r.Files[i] = col.StagePath(f)
+			}
+		}
+		for _, p := range zebedee.VerifyCollection(name) {
+			log.Info("collection failed verification after moving to review stage", log.Data{"collection": name, "problem": p})
+		}
+	}
+}
 
-		if err := col.AddArticle(a, article); err != nil {
-			e.logMigrationOutcome(err, article.VisualURL, a.URI, collectionName)
-			continue
+// migrateArticle migrates a single mapping entry, recording the outcome in the result.
+func (e *Executor) migrateArticle(article *migration.Article, r *Result, collectionURLs []string) error {
+	start := time.Now()
+	r.Durations = make(map[string]float64)
+	defer func() { r.Durations[totalDuration] = millis(time.Since(start)) }()
+
+	a, visualItem, err := Convert(e.plan, article)
+	var cy *zebedee.Article
+	if err == nil {
+		cy, err = ConvertWelsh(e.plan, a, visualItem)
+	}
+	if a != nil {
+		r.ONSURI, r.Warnings = a.URI, a.Warnings
+	}
+	r.Durations[convertDuration] = millis(time.Since(start))
+	if err != nil {
+		return err
+	}
+
+	step := time.Now()
+	publishDate, err := e.schedule.PublishDate(article)
+	if err != nil {
+		return err
+	}
+
+	_, existed := e.collections[r.CollectionName]
+	col, err := e.getCollection(r.CollectionName, collectionURLs, publishDate)
+	r.Durations[collectionDuration] = millis(time.Since(step))
+	if err != nil {
+		return err
+	}
+	r.CollectionID = col.ID
+	if !existed {
+		r.Files = append(r.Files, col.Metadata.CollectionJSON)
+	}
+
+	step = time.Now()
+	err = col.AddArticle(a, article)
+	r.Durations[writeDuration] = millis(time.Since(step))
+	if err != nil {
+		return err
+	}
+	r.Files = append(r.Files, zebedee.ArticlePath(r.CollectionName, a.URI))
+
+	if cy != nil {
+		if err := col.AddWelshArticle(cy); err != nil {
+			return err
+		}
+		r.Files = append(r.Files, zebedee.WelshArticlePath(r.CollectionName, a.URI))
+	}
+
+	step = time.Now()
+	err = col.VerifyArticle(a.URI)
+	r.Durations[verifyDuration] = millis(time.Since(step))
+	return err
+}
+
+// getCollection returns the collection with the given name, creating it the first time it is requested. If creating
+// the collection failed the same error is returned for every other row in the group. Every article in a scheduled
+// collection must have the same publish date.
+func (e *Executor) getCollection(name string, visualURLs []string, publishDate *time.Time) (*zebedee.Collection, error) {
+	if col, ok := e.collections[name]; ok {
+		if publishDate != nil && col.PublishDate != zebedee.FormatCollectionDate(*publishDate) {
+			return nil, migration.Error{
+				Message: "article publish date does not match the publish date of its collection",
+				Params:  log.Data{"collection": name, "collectionPublishDate": col.PublishDate, "publishDate": zebedee.FormatCollectionDate(*publishDate)},
+			}
 		}
-		e.logMigrationOutcome(nil, article.VisualURL, a.URI, collectionName)
+		return col, nil
+	}
+	if err, ok := e.collectionErr[name]; ok {
+		return nil, err
+	}
+
+	col, err := zebedee.CreateCollection(name, strings.Join(visualURLs, "\n"), publishDate)
+	if err != nil {
+		e.collectionErr[name] = err
+		return nil, err
 	}
+	e.collections[name] = col
+	return col, nil
 }
 
-func (e *Executor) logMigrationOutcome(err error, visualURL string, onsURL string, collectionName string) {
-	status := "SUCCESS"
-	errMsg := "N/A"
+func (e *Executor) logMigrationOutcome(r *Result, err error) {
+	r.Status = StatusSuccess
+	errMsg := noError
 	if err != nil {
-		log.ErrorC("error while processing mapping entry", err, log.Data{"rowIndex": e.currentRowIndex})
+		log.ErrorC("error while processing mapping entry", err, log.Data{"rowIndex": r.Row})
 		errMsg = err.Error()
-		status = "ERROR"
+		r.Status = StatusError
+		r.Error = errMsg
+		r.ErrorParams = errorParams(err)
+		r.ErrorCategory = errorCategory(err)
+	}
+
+	if r.Warnings == nil {
+		r.Warnings = []string{}
+	}
+	if r.Files == nil {
+		r.Files = []string{}
 	}
 
-	// take into account the header row of the csv + array being indexed from 0
-	index := e.currentRowIndex + 2
-	e.resultsWriter.Write([]string{strconv.Itoa(index), collectionName, status, visualURL, onsURL, errMsg})
-	e.currentRowIndex++
+	for _, w := range r.Warnings {
+		log.Debug("warning while processing mapping entry", log.Data{"rowIndex": r.Row, "warning": w})
+		if strings.HasPrefix(w, zebedee.UnmappedAuthorWarning) {
+			e.unmappedAuthors[w] = append(e.unmappedAuthors[w], r.Row)
+		}
+		if strings.HasPrefix(w, zebedee.NoWelshTranslationWarning) {
+			e.untranslated = append(e.untranslated, r.Row)
+		}
+	}
+
+	e.results = append(e.results, r)
+	e.resultsWriter.Write([]string{strconv.Itoa(r.Row), r.CollectionName, r.Status, r.VisualURL, r.ONSURI, errMsg, strings.Join(r.Warnings, warningSeparator)})
+}
+
+// writeJSONResults writes the results not yet written to the json results file.
+func (e *Executor) writeJSONResults() {
+	for _, r := range e.results[e.jsonWritten:] {
+		if err := e.jsonEncoder.Encode(r); err != nil {
+			log.ErrorC("failed to write json results record", err, log.Data{"rowIndex": r.Row})
+		}
+	}
+	e.jsonWritten = len(e.results)
 }
 
 func (e *Executor) Close() {
+	for author, rows := range e.unmappedAuthors {
+		log.Info("visual post author without contact details", log.Data{"warning": author, "rows": rows})
+	}
+	if len(e.untranslated) > 0 {
+		log.Info("visual posts without a welsh translation", log.Data{"rows": e.untranslated})
+	}
+
+	if e.plan.Archive.HasIndex() {
+		for _, u := range e.plan.Archive.Missing() {
+			log.Info("visual url has no national archives capture, using default snapshot", log.Data{"url": u})
+		}
+	}
+
 	log.Debug("closing executor resources", nil)
 	e.resultsWriter.Flush()
+	e.writeJSONResults()
+
+	if err := e.writeReport(); err != nil {
+		log.Error(err, nil)
+	}
+
 	e.resultsFile.Close()
+	e.jsonFile.Close()
 }
@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"text/template"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/config"
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/dp-visual-ons-migration/util"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	GroupByRow      = "row"
+	GroupByTaxonomy = "taxonomy"
+	GroupByColumn   = "column"
+	GroupBySize     = "size"
+
+	ungroupedKey = "ungrouped"
+)
+
+var (
+	// the default collection name template for each grouping mode - row grouping keeps the original viz_<row>_<title> names.
+	defaultNameTemplates = map[string]string{
+		GroupByRow:      "viz_{{.FirstRow}}_{{.Title}}",
+		GroupByTaxonomy: "viz_{{.FirstRow}}-{{.LastRow}}_{{.Key}}",
+		GroupByColumn:   "viz_{{.FirstRow}}-{{.LastRow}}_{{.Key}}",
+		GroupBySize:     "viz_{{.FirstRow}}-{{.LastRow}}",
+	}
+)
+
+// Grouping decides which collection each mapping entry in a batch is migrated into.
+type Grouping struct {
+	Mode   string
+	Column string
+	Size   int
+	name   *template.Template
+}
+
+// CollectionNameData the values available to the collection name template.
+type CollectionNameData struct {
+	Key      string
+	Index    int
+	FirstRow int
+	LastRow  int
+	Size     int
+	Title    string
+}
+
+// collectionGroup a set of mapping entries that will be migrated into the same collection.
+type collectionGroup struct {
+	key      string
+	name     string
+	articles []*migration.Article
+}
+
+// NewGrouping creates a Grouping from the collection grouping config, defaulting to one collection per row.
+func NewGrouping(cfg *config.Model) (*Grouping, error) {
+	g := &Grouping{
+		Mode:   cfg.CollectionGrouping,
+		Column: cfg.CollectionGroupColumn,
+		Size:   cfg.CollectionGroupSize,
+	}
+
+	if g.Mode == "" {
+		g.Mode = GroupByRow
+	}
+
+	nameTemplate, ok := defaultNameTemplates[g.Mode]
+	if !ok {
+		return nil, migration.Error{Message: "unknown collection grouping", Params: log.Data{"grouping": g.Mode}}
+	}
+
+	if g.Mode == GroupByColumn && g.Column == "" {
+		return nil, migration.Error{Message: "collection grouping by column requires collection-group-column", Params: nil}
+	}
+
+	if g.Mode == GroupBySize && g.Size < 1 {
+		return nil, migration.Error{Message: "collection grouping by size requires a collection-group-size greater than 0", Params: log.Data{"size": g.Size}}
+	}
+
+	if cfg.CollectionNameTemplate != "" {
+		nameTemplate = cfg.CollectionNameTemplate
+	}
+
+	t, err := template.New("collection-name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return nil, migration.Error{Message: "failed to parse collection name template", OriginalErr: err, Params: log.Data{"template": nameTemplate}}
+	}
+	g.name = t
+	return g, nil
+}
+
+// key returns the group key for the mapping entry, position is the index of the entry within the batch.
+func (g *Grouping) key(a *migration.Article, position int) string {
+	var key string
+	switch g.Mode {
+	case GroupByTaxonomy:
+		key = a.GetTaxonomyRoot()
+	case GroupByColumn:
+		key = a.Fields[g.Column]
+	case GroupBySize:
+		key = strconv.Itoa(position / g.Size)
+	default:
+		key = strconv.Itoa(a.Row)
+	}
+
+	if key = util.SanitisedFilename(key); key == "" {
+		return ungroupedKey
+	}
+	return key
+}
+
+// Assign groups the batch into collections returning the collection name for each mapping row. Groups are numbered in
+// the order they first appear in the batch. Every chapter of a compendium is assigned to the collection of its first
+// chapter, whatever the grouping.
+func (g *Grouping) Assign(batch []*migration.Article) (map[int]string, error) {
+	groups := make([]*collectionGroup, 0)
+	byKey := make(map[string]*collectionGroup)
+	// the chapters of a compendium share its landing page, so they join the group of its first chapter in the batch
+	byLandingPage := make(map[string]*collectionGroup)
+
+	for i, a := range batch {
+		landingPage := a.LandingPageURI()
+		if group, ok := byLandingPage[landingPage]; ok {
+			group.articles = append(group.articles, a)
+			continue
+		}
+
+		key := g.key(a, i)
+
+		group, ok := byKey[key]
+		if !ok {
+			group = &collectionGroup{key: key, articles: make([]*migration.Article, 0)}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.articles = append(group.articles, a)
+		if landingPage != "" {
+			byLandingPage[landingPage] = group
+		}
+	}
+
+	names := make(map[int]string)
+	used := make(map[string]string)
+
+	for i, group := range groups {
+		first := group.articles[0]
+		last := group.articles[len(group.articles)-1]
+
+		var b bytes.Buffer
+		err := g.name.Execute(&b, CollectionNameData{
+			Key:      group.key,
+			Index:    i + 1,
+			FirstRow: first.Row,
+			LastRow:  last.Row,
+			Size:     len(group.articles),
+			Title:    util.SanitisedFilename(first.PostTitle),
+		})
+		if err != nil {
+			return nil, migration.Error{Message: "failed to generate collection name", OriginalErr: err, Params: log.Data{"group": group.key}}
+		}
+
+		group.name = b.String()
+		if other, ok := used[group.name]; ok {
+			msg := fmt.Sprintf("collection name template generated the same name for groups %q and %q", other, group.key)
+			return nil, migration.Error{Message: msg, Params: log.Data{"collection": group.name}}
+		}
+		used[group.name] = group.key
+
+		for _, a := range group.articles {
+			names[a.Row] = group.name
+		}
+	}
+	return names, nil
+}
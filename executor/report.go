@@ -0,0 +1,232 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/config"
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/go-ns/log"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	reportExt        = ".html"
+	reportTimeFormat = "2006-01-02 15:04:05 MST"
+)
+
+// inputFile a file the run read and its checksum, so the report identifies exactly what was migrated.
+type inputFile struct {
+	Name   string
+	Path   string
+	SHA256 string
+	Error  string
+}
+
+type count struct {
+	Name  string
+	Count int
+}
+
+type report struct {
+	ResultsFile     string
+	Started         string
+	Finished        string
+	Total           int
+	Statuses        []count
+	ErrorCategories []count
+	Results         []*Result
+	ONSSite         string
+	Config          string
+	Inputs          []inputFile
+}
+
+// ReportPath returns the path of the HTML report written alongside the CSV results file.
+func ReportPath(resultsPath string) string {
+	return strings.TrimSuffix(resultsPath, filepath.Ext(resultsPath)) + reportExt
+}
+
+// checksumInputs returns the sha256 of each input file configured for the run.
+func checksumInputs(cfg *config.Model) []inputFile {
+	files := []inputFile{
+		{Name: "migration-file", Path: cfg.MappingFile},
+		{Name: "visual-rss-file", Path: cfg.VisualExportFile},
+		{Name: "contacts-file", Path: cfg.ContactsFile},
+		{Name: "vocabulary-file", Path: cfg.VocabularyFile},
+		{Name: "national-archives-cdx-file", Path: cfg.NationalArchivesCDXFile},
+	}
+
+	inputs := make([]inputFile, 0, len(files))
+	for _, f := range files {
+		if f.Path == "" {
+			continue
+		}
+		f.SHA256, f.Error = checksum(f.Path)
+		inputs = append(inputs, f)
+	}
+	return inputs
+}
+
+func checksum(path string) (string, string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err.Error()
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err.Error()
+	}
+	return hex.EncodeToString(h.Sum(nil)), ""
+}
+
+// writeReport writes a self-contained HTML summary of the run.
+func (e *Executor) writeReport() error {
+	r := &report{
+		ResultsFile: e.resultsFile.Name(),
+		Started:     e.started.Format(reportTimeFormat),
+		Finished:    time.Now().Format(reportTimeFormat),
+		Total:       len(e.results),
+		Results:     e.results,
+		ONSSite:     e.cfg.ONSSite,
+		Inputs:      e.inputs,
+	}
+
+	statuses := make(map[string]int)
+	categories := make(map[string]int)
+	for _, result := range e.results {
+		statuses[result.Status]++
+		if result.Status == StatusError {
+			categories[result.ErrorCategory]++
+		}
+	}
+	r.Statuses = sortCounts(statuses)
+	r.ErrorCategories = sortCounts(categories)
+
+	if b, err := yaml.Marshal(e.cfg); err == nil {
+		r.Config = string(b)
+	}
+
+	path := ReportPath(e.resultsFile.Name())
+	f, err := os.Create(path)
+	if err != nil {
+		return migration.Error{Message: "failed to create run report", OriginalErr: err, Params: log.Data{"path": path}}
+	}
+	defer f.Close()
+
+	if err := reportTemplate.Execute(f, r); err != nil {
+		return migration.Error{Message: "failed to write run report", OriginalErr: err, Params: log.Data{"path": path}}
+	}
+
+	log.Info("run report written", log.Data{"path": path})
+	return nil
+}
+
+// sortCounts orders the counts most frequent first.
+func sortCounts(m map[string]int) []count {
+	counts := make([]count, 0, len(m))
+	for name, n := range m {
+		counts = append(counts, count{Name: name, Count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Visual migration report - {{.ResultsFile}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+th.sortable { cursor: pointer; background: #f5f5f5; }
+.SUCCESS { color: #0a7a0a; }
+.ERROR { color: #c00; }
+ul { margin: 0; padding-left: 1.2em; }
+pre { background: #f5f5f5; padding: 1em; }
+</style></head>
+<body>
+<h1>Visual migration report</h1>
+<p>Results file: {{.ResultsFile}}<br>Started: {{.Started}}<br>Finished: {{.Finished}}</p>
+
+<h2>Totals</h2>
+<table>
+<tr><th>Status</th><th>Rows</th></tr>
+{{range .Statuses}}<tr><td class="{{.Name}}">{{.Name}}</td><td>{{.Count}}</td></tr>{{end}}
+<tr><th>Total</th><th>{{.Total}}</th></tr>
+</table>
+
+{{if .ErrorCategories}}<h2>Errors</h2>
+<table>
+<tr><th>Error</th><th>Rows</th></tr>
+{{range .ErrorCategories}}<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>{{end}}
+</table>{{end}}
+
+<h2>Rows</h2>
+<table id="rows">
+<thead><tr>
+<th class="sortable" data-type="number">Row</th>
+<th class="sortable">Status</th>
+<th class="sortable">Collection</th>
+<th class="sortable">ONS URI</th>
+<th class="sortable">Visual URL</th>
+<th class="sortable">Error</th>
+<th class="sortable" data-type="number">Warnings</th>
+</tr></thead>
+<tbody>
+{{range .Results}}<tr>
+<td>{{.Row}}</td>
+<td class="{{.Status}}">{{.Status}}</td>
+<td>{{.CollectionName}}</td>
+<td>{{if .ONSURI}}<a href="{{$.ONSSite}}{{.ONSURI}}">{{.ONSURI}}</a>{{end}}</td>
+<td><a href="{{.VisualURL}}">{{.VisualURL}}</a></td>
+<td>{{.Error}}</td>
+<td data-value="{{len .Warnings}}">{{if .Warnings}}<ul>{{range .Warnings}}<li>{{.}}</li>{{end}}</ul>{{end}}</td>
+</tr>{{end}}
+</tbody>
+</table>
+
+<h2>Configuration</h2>
+<pre>{{.Config}}</pre>
+
+<h2>Input files</h2>
+<table>
+<tr><th>Input</th><th>Path</th><th>SHA-256</th></tr>
+{{range .Inputs}}<tr><td>{{.Name}}</td><td>{{.Path}}</td><td>{{if .Error}}{{.Error}}{{else}}<code>{{.SHA256}}</code>{{end}}</td></tr>{{end}}
+</table>
+
+<script>
+document.querySelectorAll("#rows th.sortable").forEach(function (th, column) {
+  var ascending = true;
+  th.addEventListener("click", function () {
+    var body = document.querySelector("#rows tbody");
+    var numeric = th.dataset.type === "number";
+    var value = function (row) {
+      var cell = row.children[column];
+      var v = cell.dataset.value !== undefined ? cell.dataset.value : cell.textContent.trim();
+      return numeric ? parseFloat(v) : v.toLowerCase();
+    };
+    var rows = Array.prototype.slice.call(body.rows);
+    rows.sort(function (a, b) {
+      var x = value(a), y = value(b);
+      return (x < y ? -1 : x > y ? 1 : 0) * (ascending ? 1 : -1);
+    });
+    rows.forEach(function (row) { body.appendChild(row); });
+    ascending = !ascending;
+  });
+});
+</script>
+</body></html>
+`))
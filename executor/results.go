@@ -0,0 +1,144 @@
+package executor
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	StatusSuccess = "SUCCESS"
+	StatusError   = "ERROR"
+
+	noError          = "N/A"
+	warningSeparator = "; "
+	jsonResultsExt   = ".jsonl"
+
+	// the steps timed for each row
+	convertDuration    = "convert"
+	collectionDuration = "collection"
+	writeDuration      = "write"
+	verifyDuration     = "verify"
+	totalDuration      = "total"
+)
+
+// Result the outcome of migrating a single mapping row, as recorded in the results file. The CSV results record the
+// row, collection name, status, urls, error and warnings, the JSON Lines results record every field.
+type Result struct {
+	Row            int                `json:"row"`
+	CollectionName string             `json:"collectionName"`
+	CollectionID   string             `json:"collectionId,omitempty"`
+	Status         string             `json:"status"`
+	VisualURL      string             `json:"visualUrl"`
+	ONSURI         string             `json:"onsUri"`
+	Error          string             `json:"error,omitempty"`
+	ErrorParams    log.Data           `json:"errorParams,omitempty"`
+	ErrorCategory  string             `json:"errorCategory,omitempty"`
+	Warnings       []string           `json:"warnings"`
+	Durations      map[string]float64 `json:"durationsMs,omitempty"`
+	Files          []string           `json:"files"`
+}
+
+// JSONResultsPath returns the path of the JSON Lines results written alongside the CSV results file.
+func JSONResultsPath(resultsPath string) string {
+	return strings.TrimSuffix(resultsPath, filepath.Ext(resultsPath)) + jsonResultsExt
+}
+
+// errorParams returns the params of the error and the errors it wraps, the outermost error taking precedence.
+func errorParams(err error) log.Data {
+	params := log.Data{}
+	for err != nil {
+		migrationErr, ok := err.(migration.Error)
+		if !ok {
+			break
+		}
+		for k, v := range migrationErr.Params {
+			if _, ok := params[k]; !ok {
+				params[k] = v
+			}
+		}
+		err = migrationErr.OriginalErr
+	}
+
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// errorCategory groups errors by the message of the outermost migration error, without the details of the error it
+// wraps.
+func errorCategory(err error) string {
+	if migrationErr, ok := err.(migration.Error); ok && migrationErr.Message != "" {
+		return strings.TrimSpace(migrationErr.Message)
+	}
+	return err.Error()
+}
+
+// millis returns the duration in milliseconds to microsecond precision.
+func millis(d time.Duration) float64 {
+	return float64(d/time.Microsecond) / 1000
+}
+
+// ReadResults reads the results file written by a previous migration run.
+func ReadResults(filename string) ([]*Result, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, migration.Error{Message: "error while attempting to open results file", OriginalErr: err, Params: log.Data{"filename": filename}}
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	results := make([]*Result, 0)
+	isHeader := true
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, migration.Error{Message: "error while reading results file", OriginalErr: err, Params: log.Data{"filename": filename}}
+		}
+
+		if isHeader {
+			isHeader = false
+			continue
+		}
+
+		if len(row) < 6 {
+			return nil, migration.Error{Message: "invalid results file row", Params: log.Data{"filename": filename, "row": row}}
+		}
+
+		index, err := strconv.Atoi(row[0])
+		if err != nil {
+			return nil, migration.Error{Message: "invalid results file row index", OriginalErr: err, Params: log.Data{"filename": filename, "row": row}}
+		}
+
+		r := &Result{
+			Row:            index,
+			CollectionName: row[1],
+			Status:         row[2],
+			VisualURL:      row[3],
+			ONSURI:         row[4],
+			Error:          row[5],
+			Warnings:       []string{},
+		}
+		if r.Error == noError {
+			r.Error = ""
+		}
+		if len(row) > 6 && row[6] != "" {
+			r.Warnings = strings.Split(row[6], warningSeparator)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"time"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/config"
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	londonTimezone = "Europe/London"
+
+	// ONS releases are published at 09:30 UK time - used when a publish date has no time of day.
+	defaultReleaseHour   = 9
+	defaultReleaseMinute = 30
+)
+
+var (
+	publishDateTimeFormats = []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02 15:04:05", "02/01/2006 15:04"}
+	publishDateFormats     = []string{"2006-01-02", "02/01/2006"}
+)
+
+// Schedule decides the type and publish date of the collections created by a migration run.
+type Schedule struct {
+	Type       string
+	fixedDate  *time.Time
+	dateColumn string
+	location   *time.Location
+	now        time.Time
+}
+
+// NewSchedule creates a Schedule from the collection config. Manual collections have no publish date, scheduled
+// collections are published either on the fixed publish-date or on the date in the publish-date-column of the mapping.
+func NewSchedule(cfg *config.Model, now time.Time) (*Schedule, error) {
+	s := &Schedule{Type: cfg.CollectionType, dateColumn: cfg.PublishDateColumn, now: now}
+
+	if s.Type == "" {
+		s.Type = zebedee.ManualCollection
+	}
+
+	switch s.Type {
+	case zebedee.ManualCollection:
+		if cfg.PublishDate != "" || cfg.PublishDateColumn != "" {
+			return nil, migration.Error{Message: "publish-date and publish-date-column can only be used with scheduled collections", Params: nil}
+		}
+		return s, nil
+	case zebedee.ScheduledCollection:
+	default:
+		return nil, migration.Error{Message: "unknown collection type", Params: log.Data{"type": s.Type}}
+	}
+
+	if (cfg.PublishDate == "") == (cfg.PublishDateColumn == "") {
+		return nil, migration.Error{Message: "scheduled collections require exactly one of publish-date or publish-date-column", Params: nil}
+	}
+
+	loc, err := time.LoadLocation(londonTimezone)
+	if err != nil {
+		return nil, migration.Error{Message: "failed to load timezone", OriginalErr: err, Params: log.Data{"timezone": londonTimezone}}
+	}
+	s.location = loc
+
+	if cfg.PublishDate != "" {
+		date, err := s.parse(cfg.PublishDate)
+		if err != nil {
+			return nil, err
+		}
+		s.fixedDate = &date
+	}
+	return s, nil
+}
+
+// PublishDate returns the UTC time the collection containing the mapping entry should be published, or nil if the
+// collection is manual.
+func (s *Schedule) PublishDate(a *migration.Article) (*time.Time, error) {
+	if s.Type == zebedee.ManualCollection {
+		return nil, nil
+	}
+
+	if s.fixedDate != nil {
+		return s.fixedDate, nil
+	}
+
+	value := a.Fields[s.dateColumn]
+	if value == "" {
+		return nil, migration.Error{Message: "mapping entry has no publish date", Params: log.Data{"column": s.dateColumn, "row": a.Row}}
+	}
+
+	date, err := s.parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+// parse a publish date written in UK local time, returning it in UTC. The date must be in the future.
+func (s *Schedule) parse(value string) (time.Time, error) {
+	var date time.Time
+	var err error
+	parsed := false
+
+	for _, layout := range publishDateTimeFormats {
+		if date, err = time.ParseInLocation(layout, value, s.location); err == nil {
+			parsed = true
+			break
+		}
+	}
+
+	if !parsed {
+		for _, layout := range publishDateFormats {
+			if date, err = time.ParseInLocation(layout, value, s.location); err == nil {
+				date = time.Date(date.Year(), date.Month(), date.Day(), defaultReleaseHour, defaultReleaseMinute, 0, 0, s.location)
+				parsed = true
+				break
+			}
+		}
+	}
+
+	if !parsed {
+		return time.Time{}, migration.Error{Message: "failed to parse publish date", OriginalErr: err, Params: log.Data{"date": value}}
+	}
+
+	if !date.After(s.now) {
+		return time.Time{}, migration.Error{Message: "publish date must be in the future", Params: log.Data{"date": value}}
+	}
+	return date.UTC(), nil
+}
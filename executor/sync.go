@@ -0,0 +1,242 @@
+package executor
+
+import (
+	"encoding/csv"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	// what sync did with each post
+	ActionUpdated       = "updated"
+	ActionMigrated      = "migrated"
+	ActionNone          = "none"
+	ActionNotMapped     = "not-mapped"
+	ActionNotInManifest = "not-in-manifest"
+	ActionReview        = "review"
+
+	// added to the name of a new collection that has the name of an existing collection
+	syncSuffix = "_sync"
+)
+
+var (
+	changesFileHeader = []string{"POST_ID", "VISUAL_URL", "TITLE", "CHANGE", "ACTION", "MAPPING_ROW_INDEX", "COLLECTION_NAME", "PREVIOUS_ONS_URL"}
+)
+
+// SyncEntry a post of the newer export and what sync did with it.
+type SyncEntry struct {
+	*migration.PostChange
+	Action         string
+	Row            int
+	CollectionName string
+	// the uri the previous run migrated the post to
+	PreviousONSURI string
+}
+
+// Sync re-migrates the posts changed or added since a previous run. The manifest is the results file of that run:
+// changed posts it migrated successfully are regenerated in their existing collection if it is still in progress, are
+// reported for review if it has been completed or reviewed, or are migrated into a new collection if it no longer
+// exists. Mapped posts added to the export are migrated into new collections, named with a _sync suffix if the name
+// is taken.
+func (e *Executor) Sync(manifest []*Result, changes []*migration.PostChange) []*SyncEntry {
+	migrated := make(map[string]*Result)
+	for _, r := range manifest {
+		if r.Status == StatusSuccess {
+			migrated[migration.NormaliseURL(r.VisualURL)] = r
+		}
+	}
+
+	entries := make([]*SyncEntry, 0, len(changes))
+	existing := make(map[int]string)
+	// the previous uri of each updated post now migrated to a different uri, removed once the post is migrated
+	moved := make(map[int]string)
+	batch := make([]*migration.Article, 0)
+
+	for _, c := range changes {
+		entry := &SyncEntry{PostChange: c, Action: ActionNone}
+		entries = append(entries, entry)
+
+		previous, inManifest := migrated[migration.NormaliseURL(c.URL)]
+		if inManifest {
+			entry.CollectionName, entry.PreviousONSURI = previous.CollectionName, previous.ONSURI
+		}
+
+		if c.Change == migration.PostDeleted {
+			if inManifest {
+				entry.Action = ActionReview
+				log.Info("visual post deleted since the previous run, its article must be removed manually", log.Data{"url": c.URL, "collection": previous.CollectionName, "uri": previous.ONSURI})
+			}
+			continue
+		}
+
+		if c.Change == migration.PostUnchanged {
+			continue
+		}
+
+		article, ok := e.plan.Mapping.GetArticleByURL(c.URL)
+		if !ok {
+			entry.Action = ActionNotMapped
+			continue
+		}
+		entry.Row = article.Row
+
+		if c.Change == migration.PostChanged && !inManifest {
+			entry.Action = ActionNotInManifest
+			continue
+		}
+
+		entry.Action = ActionMigrated
+		if inManifest {
+			col, err := zebedee.OpenCollection(previous.CollectionName)
+			if err == nil && !col.IsInProgress() {
+				entry.Action = ActionReview
+				log.Info("collection of the previous run has been completed or reviewed, its article must be updated manually", log.Data{"url": c.URL, "collection": col.Name, "uri": previous.ONSURI})
+				continue
+			}
+
+			if err == nil {
+				if previous.ONSURI != article.TaxonomyURI {
+					if err := checkRemovable(col, previous.ONSURI); err != nil {
+						entry.Action = ActionReview
+						log.Info("visual post now migrates to a different uri, its article must be moved manually", log.Data{"url": c.URL, "collection": col.Name, "uri": previous.ONSURI, "new_uri": article.TaxonomyURI, "reason": err.Error()})
+						continue
+					}
+					moved[article.Row] = previous.ONSURI
+				}
+				e.collections[col.Name] = col
+				existing[article.Row] = col.Name
+				entry.Action = ActionUpdated
+			} else {
+				log.Info("collection of the previous run no longer exists, migrating into a new collection", log.Data{"url": c.URL, "collection": previous.CollectionName})
+			}
+		}
+		batch = append(batch, article)
+	}
+
+	// migrate in mapping order as a batch would
+	sort.Slice(batch, func(i, j int) bool { return batch[i].Row < batch[j].Row })
+
+	newBatch := make([]*migration.Article, 0)
+	for _, a := range batch {
+		if _, ok := existing[a.Row]; !ok {
+			newBatch = append(newBatch, a)
+		}
+	}
+
+	collectionNames, err := e.grouping.Assign(newBatch)
+	if err != nil {
+		for _, article := range newBatch {
+			e.logMigrationOutcome(&Result{Row: article.Row, VisualURL: article.VisualURL}, err)
+		}
+		collectionNames = map[int]string{}
+
+		// only the posts updated in their existing collection can still be migrated
+		updated := make([]*migration.Article, 0, len(batch))
+		for _, a := range batch {
+			if _, ok := existing[a.Row]; ok {
+				updated = append(updated, a)
+			}
+		}
+		batch = updated
+	}
+	renameExisting(collectionNames)
+	for row, name := range existing {
+		collectionNames[row] = name
+	}
+
+	log.Info("syncing changed visual posts", log.Data{"updated": len(existing), "migrated": len(newBatch)})
+	first := len(e.results)
+	e.migrateBatch(batch, collectionNames)
+	e.removeMoved(e.results[first:], moved)
+
+	for _, entry := range entries {
+		if entry.Action == ActionMigrated || entry.Action == ActionUpdated {
+			entry.CollectionName = collectionNames[entry.Row]
+		}
+	}
+	return entries
+}
+
+// renameExisting gives a new collection that has the name of an existing collection, such as one created by the
+// previous run, the first free name with a _sync suffix.
+func renameExisting(collectionNames map[int]string) {
+	renamed := make(map[string]string)
+	for row, name := range collectionNames {
+		if _, ok := renamed[name]; !ok {
+			renamed[name] = name
+			for n := 1; zebedee.CollectionExists(renamed[name]); n++ {
+				renamed[name] = name + syncSuffix
+				if n > 1 {
+					renamed[name] += strconv.Itoa(n)
+				}
+			}
+		}
+		collectionNames[row] = renamed[name]
+	}
+}
+
+// checkRemovable returns an error if the article at the uri in the collection can not be removed when its post is
+// migrated to a new uri - a compendium chapter is also listed on its landing page.
+func checkRemovable(col *zebedee.Collection, uri string) error {
+	a, err := zebedee.ReadArticle(col.Name, uri)
+	if err != nil {
+		return err
+	}
+	if a.Type == migration.PageCompendiumChapter {
+		return migration.Error{Message: "compendium chapter is listed on its landing page", Params: log.Data{"uri": uri}}
+	}
+	return nil
+}
+
+// removeMoved removes the article at the previous uri of each post migrated successfully to a new uri in its existing
+// collection, so the old page is not published alongside the new one.
+func (e *Executor) removeMoved(results []*Result, moved map[int]string) {
+	for _, r := range results {
+		uri, ok := moved[r.Row]
+		if !ok || r.Status != StatusSuccess {
+			continue
+		}
+
+		if err := e.collections[r.CollectionName].RemoveArticle(uri); err != nil {
+			log.ErrorC("failed to remove article from its previous uri, it must be removed manually", err, log.Data{"collection": r.CollectionName, "uri": uri})
+			continue
+		}
+		log.Info("removed article from its previous uri", log.Data{"collection": r.CollectionName, "uri": uri, "new_uri": r.ONSURI})
+	}
+}
+
+// WriteSyncReport writes the change and sync action of every post to a csv file.
+func WriteSyncReport(filename string, entries []*SyncEntry) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return migration.Error{Message: "failed to create sync report", OriginalErr: err, Params: log.Data{"path": filename}}
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	defer w.Flush()
+	w.Write(changesFileHeader)
+
+	counts := make(map[string]int)
+	for _, e := range entries {
+		row := ""
+		if e.Row > 0 {
+			row = strconv.Itoa(e.Row)
+		}
+		w.Write([]string{e.ID, e.URL, e.Title, e.Change, e.Action, row, e.CollectionName, e.PreviousONSURI})
+
+		counts[e.Change]++
+	}
+
+	data := log.Data{"report": filename}
+	for change, n := range counts {
+		data[change] = n
+	}
+	log.Info("sync complete", data)
+	return nil
+}
@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+// Validate runs the migration of the batch in memory without writing anything. Each row must convert to a page matching
+// the schema of its page type, have a publish date matching the rest of its collection and migrate to a URI no other row
+// in the batch uses, and its collection must not already exist. Welsh translations are converted and validated too.
+func Validate(plan *migration.Plan, grouping *Grouping, schedule *Schedule, batch []*migration.Article) []*Result {
+	results := make([]*Result, 0, len(batch))
+
+	collectionNames, err := grouping.Assign(batch)
+	if err != nil {
+		for _, article := range batch {
+			results = append(results, validated(&Result{Row: article.Row, VisualURL: article.VisualURL}, err))
+		}
+		return results
+	}
+
+	publishDates := make(map[string]string)
+	uris := make(map[string]int)
+
+	for _, article := range batch {
+		r := &Result{Row: article.Row, CollectionName: collectionNames[article.Row], VisualURL: article.VisualURL}
+		results = append(results, validated(r, validateArticle(plan, schedule, article, r, publishDates, uris)))
+	}
+	return results
+}
+
+func validateArticle(plan *migration.Plan, schedule *Schedule, article *migration.Article, r *Result, publishDates map[string]string, uris map[string]int) error {
+	a, visualItem, err := Convert(plan, article)
+	var cy *zebedee.Article
+	if err == nil {
+		cy, err = ConvertWelsh(plan, a, visualItem)
+	}
+	if a != nil {
+		r.ONSURI, r.Warnings = a.URI, a.Warnings
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := validatePage(a, "article does not match the schema of its page type"); err != nil {
+		return err
+	}
+	if cy != nil {
+		if err := validatePage(cy, "welsh article does not match the schema of its page type"); err != nil {
+			return err
+		}
+	}
+
+	if row, ok := uris[a.URI]; ok {
+		return migration.Error{Message: "another row in the batch migrates to the same ONS URI", Params: log.Data{"uri": a.URI, "row": row}}
+	}
+	uris[a.URI] = article.Row
+
+	publishDate, err := schedule.PublishDate(article)
+	if err != nil {
+		return err
+	}
+
+	date := ""
+	if publishDate != nil {
+		date = zebedee.FormatCollectionDate(*publishDate)
+	}
+	if collectionDate, ok := publishDates[r.CollectionName]; ok && collectionDate != date {
+		return migration.Error{
+			Message: "article publish date does not match the publish date of its collection",
+			Params:  log.Data{"collection": r.CollectionName, "collectionPublishDate": collectionDate, "publishDate": date},
+		}
+	}
+	publishDates[r.CollectionName] = date
+
+	if zebedee.CollectionExists(r.CollectionName) {
+		return migration.Error{Message: "the collection already exists", Params: log.Data{"collection": r.CollectionName}}
+	}
+	return nil
+}
+
+func validatePage(a *zebedee.Article, message string) error {
+	b, err := zebedee.MarshalArticle(a)
+	if err != nil {
+		return err
+	}
+	if problems := zebedee.ValidatePage(a.Type, b); len(problems) > 0 {
+		return migration.Error{Message: message, Params: log.Data{"uri": a.URI, "type": a.Type, "problems": problems}}
+	}
+	return nil
+}
+
+func validated(r *Result, err error) *Result {
+	r.Status = StatusSuccess
+	if err != nil {
+		r.Status = StatusError
+		r.Error = err.Error()
+		r.ErrorParams = errorParams(err)
+		r.ErrorCategory = errorCategory(err)
+	}
+	if r.Warnings == nil {
+		r.Warnings = []string{}
+	}
+	return r
+}
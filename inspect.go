@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/executor"
+	"github.com/ONSdigital/dp-visual-ons-migration/inspect"
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"github.com/pkg/errors"
+)
+
+// inspectPost converts a single post in memory and prints the data.json, collection json, markdown, links and warnings
+// it would be migrated with.
+func inspectPost(args []string) {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	loadConfig := configFlags(flags, "the config to use when converting the post")
+	stage := flags.String("stage", "", "print the markdown after a conversion stage ("+strings.Join(zebedee.Stages, ", ")+") or "+inspect.AllStages)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		exit(errors.New("inspect requires a visual url, post id or title"))
+	}
+
+	if *stage != "" && *stage != inspect.AllStages && !contains(zebedee.Stages, *stage) {
+		exit(errors.Errorf("unknown conversion stage %q", *stage))
+	}
+
+	cfg, plan := loadConfig()
+
+	grouping, err := executor.NewGrouping(cfg)
+	if err != nil {
+		exit(err)
+	}
+
+	schedule, err := executor.NewSchedule(cfg, time.Now())
+	if err != nil {
+		exit(err)
+	}
+
+	i := inspect.New(plan, grouping, schedule, cfg.PublishedContentDir)
+
+	article, err := i.Find(flags.Arg(0))
+	if err != nil {
+		exit(err)
+	}
+
+	if err := i.Run(article, *stage, os.Stdout); err != nil {
+		exit(err)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package inspect
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/executor"
+	"github.com/ONSdigital/dp-visual-ons-migration/linkcheck"
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"github.com/ONSdigital/go-ns/log"
+	"golang.org/x/net/html"
+)
+
+const (
+	AllStages = "all"
+)
+
+// Link an outgoing link of the article before and after it was rewritten.
+type Link struct {
+	*linkcheck.Link
+	Original string
+}
+
+// Inspector runs the migration of a single post in memory and prints every output of the pipeline. Nothing is written
+// to the collections directory.
+type Inspector struct {
+	plan     *migration.Plan
+	grouping *executor.Grouping
+	schedule *executor.Schedule
+	checker  *linkcheck.Checker
+}
+
+func New(plan *migration.Plan, grouping *executor.Grouping, schedule *executor.Schedule, contentDir string) *Inspector {
+	return &Inspector{
+		plan:     plan,
+		grouping: grouping,
+		schedule: schedule,
+		checker:  linkcheck.New(plan, contentDir),
+	}
+}
+
+// Find returns the mapping entry for the query - a visual URL, a wordpress post ID or a case insensitive substring of
+// the post title. A title matching more than one post is an error.
+func (i *Inspector) Find(query string) (*migration.Article, error) {
+	if a, ok := i.plan.Mapping.GetArticleByURL(query); ok {
+		return a, nil
+	}
+
+	if post, ok := i.plan.VisualExport.GetPostByID(query); ok {
+		if a, ok := i.plan.Mapping.GetArticleByURL(post.Link); ok {
+			return a, nil
+		}
+		return nil, migration.Error{Message: "visual post is not mapped for migration", Params: log.Data{"id": query, "url": post.Link}}
+	}
+
+	if _, ok := i.plan.VisualExport.GetPost(query); ok {
+		return nil, migration.Error{Message: "visual post is not mapped for migration", Params: log.Data{"url": query}}
+	}
+
+	matches := make([]*migration.Article, 0)
+	for _, a := range i.plan.Mapping.ToMigrate {
+		if strings.Contains(strings.ToLower(a.PostTitle), strings.ToLower(query)) {
+			matches = append(matches, a)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, migration.Error{Message: "no mapped post matches the url, post id or title", Params: log.Data{"query": query}}
+	case 1:
+		return matches[0], nil
+	default:
+		titles := make([]string, 0, len(matches))
+		for _, a := range matches {
+			titles = append(titles, fmt.Sprintf("%d: %s", a.Row, a.PostTitle))
+		}
+		return nil, migration.Error{Message: "more than one mapped post matches the title", Params: log.Data{"query": query, "matches": titles}}
+	}
+}
+
+// Run converts the mapping entry and writes the result to w. If stage is a conversion stage (or AllStages) the
+// markdown of each section is also written after that step.
+func (i *Inspector) Run(article *migration.Article, stage string, w io.Writer) error {
+	heading(w, "post")
+	fmt.Fprintf(w, "row:         %d\n", article.Row)
+	fmt.Fprintf(w, "title:       %s\n", article.PostTitle)
+	fmt.Fprintf(w, "visual url:  %s\n", article.VisualURL)
+	fmt.Fprintf(w, "taxonomy:    %s\n", article.TaxonomyURI)
+
+	if err := article.Valid(); err != nil {
+		return err
+	}
+
+	visualItem, ok := i.plan.VisualExport.GetPost(article.VisualURL)
+	if !ok {
+		return migration.Error{Message: "visual post not found in the export", Params: log.Data{"visualURL": article.VisualURL}}
+	}
+	fmt.Fprintf(w, "post id:     %s\n", migration.GetPostID(visualItem))
+
+	names, err := i.grouping.Assign([]*migration.Article{article})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "collection:  %s\n", names[article.Row])
+
+	a, err := zebedee.CreateArticle(i.plan, article, visualItem)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "ons uri:     %s\n", a.URI)
+
+	originals := originalLinks(a)
+
+	err = a.ConvertToONSFormatStages(i.plan, func(name string, section int, markdown string) {
+		if stage == AllStages || stage == name {
+			heading(w, fmt.Sprintf("stage %s (section %d)", name, section+1))
+			fmt.Fprintln(w, markdown)
+		}
+	})
+
+	heading(w, "warnings")
+	for _, warning := range a.Warnings {
+		fmt.Fprintln(w, warning)
+	}
+
+	if err != nil {
+		return migration.Error{Message: "error converting visual post to ONS format", OriginalErr: err, Params: log.Data{"title": visualItem.Title}}
+	}
+
+	heading(w, "links")
+	i.writeLinks(w, i.rewriteLinks(a, originals))
+
+	for n, s := range a.Sections {
+		heading(w, fmt.Sprintf("markdown (section %d)", n+1))
+		fmt.Fprintln(w, s.Markdown)
+	}
+
+	b, err := zebedee.MarshalArticle(a)
+	if err != nil {
+		return err
+	}
+	heading(w, "data.json")
+	fmt.Fprintln(w, string(b))
+
+	publishDate, err := i.schedule.PublishDate(article)
+	if err != nil {
+		return err
+	}
+
+	b, err = zebedee.MarshalCollection(zebedee.NewCollection(names[article.Row], article.VisualURL, publishDate))
+	if err != nil {
+		return err
+	}
+	heading(w, "collection json")
+	fmt.Fprintln(w, string(b))
+	return nil
+}
+
+// originalLinks returns the links of the article before conversion - links, images and iframes in the wordpress html
+// and the related links.
+func originalLinks(a *zebedee.Article) []*Link {
+	links := make([]*Link, 0)
+	for _, s := range a.Sections {
+		z := html.NewTokenizer(strings.NewReader(s.Markdown))
+		for {
+			tt := z.Next()
+			if tt == html.ErrorToken {
+				break
+			}
+			if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+				continue
+			}
+
+			t := z.Token()
+			switch t.Data {
+			case "a":
+				links = append(links, newLink(linkcheck.BodyLink, attr(t, "href")))
+			case "img":
+				links = append(links, newLink(linkcheck.Image, attr(t, "src")))
+			case "iframe":
+				links = append(links, newLink(linkcheck.Iframe, attr(t, "src")))
+			}
+		}
+	}
+
+	for _, l := range a.Links {
+		links = append(links, newLink(linkcheck.RelatedLink, l.URI))
+	}
+	return links
+}
+
+// rewriteLinks sets the URL each link is migrated to, as the converter rewrites it, and checks it.
+func (i *Inspector) rewriteLinks(a *zebedee.Article, links []*Link) []*Link {
+	related := 0
+	for _, l := range links {
+		switch l.Source {
+		case linkcheck.BodyLink, linkcheck.Image:
+			if uri, err := i.plan.GetMigratedURL(l.Original); err == nil {
+				l.URL = uri
+			}
+		case linkcheck.RelatedLink:
+			if related < len(a.Links) {
+				l.URL = a.Links[related].URI
+			}
+			related++
+		}
+		i.checker.Check(l.Link)
+	}
+	return links
+}
+
+func (i *Inspector) writeLinks(w io.Writer, links []*Link) {
+	t := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(t, "SOURCE\tKIND\tSTATUS\tORIGINAL\tREWRITTEN\tDETAILS")
+	for _, l := range links {
+		rewritten := l.URL
+		if rewritten == l.Original {
+			rewritten = "-"
+		}
+		fmt.Fprintf(t, "%s\t%s\t%s\t%s\t%s\t%s\n", l.Source, l.Kind, l.Status, l.Original, rewritten, l.Details)
+	}
+	t.Flush()
+}
+
+func newLink(source string, url string) *Link {
+	return &Link{Link: &linkcheck.Link{Source: source, URL: url}, Original: url}
+}
+
+func attr(t html.Token, name string) string {
+	for _, a := range t.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func heading(w io.Writer, title string) {
+	fmt.Fprintf(w, "\n==== %s ====\n", title)
+}
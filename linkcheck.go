@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/linkcheck"
+	"github.com/pkg/errors"
+)
+
+// linkCheck checks every outgoing link of the articles migrated by a previous run without any network requests.
+func linkCheck(args []string) {
+	flags := flag.NewFlagSet("linkcheck", flag.ExitOnError)
+	loadConfig := configFlags(flags, "the config used when running the migration")
+	resultsFile := flags.String("results", "", "the results file of the migration run to check")
+	outputFile := flags.String("out", "", "the link check csv to write, defaults to the results file name with a _links suffix")
+	all := flags.Bool("all", false, "write every link to the output, not only broken and suspicious links")
+	flags.Parse(args)
+
+	if *resultsFile == "" {
+		flags.Usage()
+		exit(errors.New("linkcheck requires a -results file"))
+	}
+
+	if *outputFile == "" {
+		*outputFile = strings.TrimSuffix(*resultsFile, ".csv") + "_links.csv"
+	}
+
+	cfg, plan := loadConfig()
+
+	if err := linkcheck.New(plan, cfg.PublishedContentDir).Run(*resultsFile, *outputFile, *all); err != nil {
+		exit(err)
+	}
+}
@@ -0,0 +1,227 @@
+package linkcheck
+
+import (
+	"encoding/csv"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/executor"
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	// where in the article the link was found
+	BodyLink    = "body-link"
+	Image       = "image"
+	Iframe      = "iframe"
+	RelatedLink = "related-link"
+
+	// the kinds of link
+	MigratedPost     = "migrated-post"
+	StaticAsset      = "static-asset"
+	NationalArchives = "national-archives"
+	ONSInternal      = "ons-internal"
+	External         = "external"
+
+	StatusOK         = "OK"
+	StatusBroken     = "BROKEN"
+	StatusSuspicious = "SUSPICIOUS"
+
+	legacyONSPath = "/ons/"
+	dataJSON      = "data.json"
+)
+
+var (
+	linksFileHeader = []string{"MAPPING_ROW_INDEX", "COLLECTION_NAME", "ONS_URL", "SOURCE", "KIND", "LINK", "STATUS", "DETAILS"}
+
+	markdownLinkRX = regexp.MustCompile(`(?m)^\s*\[\d+\]: (.*)$`)
+	imageRX        = regexp.MustCompile(`<img src="([^"]*)"`)
+	iframeRX       = regexp.MustCompile(`<ons-interactive url="([^"]*)"`)
+	archivedURLRX  = regexp.MustCompile(`/\d{14}/(.+)$`)
+)
+
+// Link an outgoing link from a migrated article.
+type Link struct {
+	Source  string
+	URL     string
+	Kind    string
+	Status  string
+	Details string
+}
+
+// Checker validates the links in migrated articles without making any network requests. ONS internal links are
+// checked against a local copy of the published content and the URIs the plan migrates posts to.
+type Checker struct {
+	plan                 *migration.Plan
+	contentDir           string
+	nationalArchivesHost string
+}
+
+func New(plan *migration.Plan, contentDir string) *Checker {
+	c := &Checker{plan: plan, contentDir: contentDir}
+	if u, err := url.Parse(plan.NationalArchivesURL); err == nil {
+		c.nationalArchivesHost = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	}
+	return c
+}
+
+// Links returns every link in the article - body links, images, iframes and related links.
+func Links(a *zebedee.Article) []*Link {
+	links := make([]*Link, 0)
+	for _, s := range a.Sections {
+		for _, m := range markdownLinkRX.FindAllStringSubmatch(s.Markdown, -1) {
+			links = append(links, &Link{Source: BodyLink, URL: strings.TrimSpace(m[1])})
+		}
+		for _, m := range imageRX.FindAllStringSubmatch(s.Markdown, -1) {
+			links = append(links, &Link{Source: Image, URL: m[1]})
+		}
+		for _, m := range iframeRX.FindAllStringSubmatch(s.Markdown, -1) {
+			links = append(links, &Link{Source: Iframe, URL: m[1]})
+		}
+	}
+	for _, l := range a.Links {
+		links = append(links, &Link{Source: RelatedLink, URL: l.URI})
+	}
+	return links
+}
+
+// Check classifies the link and validates it where possible.
+func (c *Checker) Check(l *Link) {
+	l.Status = StatusOK
+
+	if l.URL == "" {
+		l.Kind = External
+		l.Status, l.Details = StatusBroken, "empty link"
+		return
+	}
+
+	u, err := url.Parse(l.URL)
+	if err != nil {
+		l.Kind = External
+		l.Status, l.Details = StatusBroken, "invalid url: "+err.Error()
+		return
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+
+	switch {
+	case u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "mailto":
+		l.Kind = External
+		l.Status, l.Details = StatusBroken, "unsupported url scheme "+u.Scheme
+	case c.plan.Sites.IsStaticONSHost(host):
+		l.Kind = StaticAsset
+	case host != "" && host == c.nationalArchivesHost:
+		l.Kind = NationalArchives
+		c.checkArchive(l)
+	case c.plan.Sites.IsONSHost(host) || (u.Scheme == "" && u.Host == "" && strings.HasPrefix(u.Path, "/")):
+		c.checkONS(l, u)
+	case u.Scheme == "" && u.Host == "":
+		l.Kind = External
+		l.Status, l.Details = StatusBroken, "relative link"
+	default:
+		l.Kind = External
+		if u.Scheme == "http" {
+			l.Status, l.Details = StatusSuspicious, "insecure http link"
+		}
+	}
+}
+
+// checkArchive checks the archived url has a capture in the national archives CDX index, if one was loaded.
+func (c *Checker) checkArchive(l *Link) {
+	if !c.plan.Archive.HasIndex() {
+		return
+	}
+
+	match := archivedURLRX.FindStringSubmatch(l.URL)
+	if match == nil {
+		l.Status, l.Details = StatusSuspicious, "national archives url without a capture timestamp"
+		return
+	}
+
+	if !c.plan.Archive.HasCaptures(match[1]) {
+		l.Status, l.Details = StatusBroken, "no national archives capture of "+match[1]
+	}
+}
+
+func (c *Checker) checkONS(l *Link, u *url.URL) {
+	path := strings.TrimSuffix(u.Path, "/")
+
+	if _, ok := c.plan.Mapping.GetArticleByURI(path); ok {
+		l.Kind = MigratedPost
+		return
+	}
+
+	l.Kind = ONSInternal
+	if path == "" {
+		return
+	}
+
+	if strings.HasPrefix(path, legacyONSPath) {
+		l.Status, l.Details = StatusSuspicious, "legacy ONS website url relies on a redirect"
+		return
+	}
+
+	if c.contentDir == "" {
+		l.Status, l.Details = StatusSuspicious, "no published content directory to check against"
+		return
+	}
+
+	for _, candidate := range []string{filepath.Join(c.contentDir, path, dataJSON), filepath.Join(c.contentDir, path)} {
+		if _, err := os.Stat(candidate); err == nil {
+			return
+		}
+	}
+	l.Status, l.Details = StatusBroken, "not found in published content"
+}
+
+// Run checks the links of every successfully migrated row in the results file, writing the broken and suspicious
+// links (or every link if all is true) to the output file.
+func (c *Checker) Run(resultsFile string, outputFile string, all bool) error {
+	results, err := executor.ReadResults(resultsFile)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return migration.Error{Message: "failed to create link check file", OriginalErr: err, Params: log.Data{"path": outputFile}}
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	defer w.Flush()
+	w.Write(linksFileHeader)
+
+	counts := make(map[string]int)
+	for _, r := range results {
+		if r.Status != executor.StatusSuccess {
+			continue
+		}
+
+		a, err := zebedee.ReadArticle(r.CollectionName, r.ONSURI)
+		if err != nil {
+			log.ErrorC("failed to read migrated article", err, log.Data{"row": r.Row})
+			w.Write([]string{strconv.Itoa(r.Row), r.CollectionName, r.ONSURI, "", "", "", StatusBroken, err.Error()})
+			counts[StatusBroken]++
+			continue
+		}
+
+		for _, l := range Links(a) {
+			c.Check(l)
+			counts[l.Status]++
+			if l.Status == StatusOK && !all {
+				continue
+			}
+			w.Write([]string{strconv.Itoa(r.Row), r.CollectionName, r.ONSURI, l.Source, l.Kind, l.URL, l.Status, l.Details})
+		}
+	}
+
+	log.Info("link check complete", log.Data{"ok": counts[StatusOK], "broken": counts[StatusBroken], "suspicious": counts[StatusSuspicious], "output": outputFile})
+	return nil
+}
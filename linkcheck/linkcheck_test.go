@@ -0,0 +1,98 @@
+package linkcheck
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+)
+
+const (
+	promotedCollection = "viz_2_promoted"
+	promotedURI        = "/economy/articles/promoted/2017-01-01"
+)
+
+// promotedResults creates a collection with one article moved to the reviewed stage, returning the path of a results
+// file listing it.
+func promotedResults(t *testing.T, root string) string {
+	zebedee.CollectionsRoot = root
+	zebedee.CompletedBy, zebedee.ReviewedBy = "completed@ons.gov.uk", "reviewed@ons.gov.uk"
+	defer func() { zebedee.CompletedBy, zebedee.ReviewedBy = "", "" }()
+
+	col, err := zebedee.CreateCollection(promotedCollection, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &zebedee.Article{
+		Type:        migration.PageArticle,
+		URI:         promotedURI,
+		Description: zebedee.Description{Title: "Promoted"},
+		Sections:    []*zebedee.MarkdownSection{},
+		Links:       []*zebedee.RelatedLink{{Title: "External", URI: "https://www.example.com/report"}},
+	}
+	if err := col.AddArticle(a, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := col.Promote(zebedee.StageReviewed, time.Date(2017, 1, 1, 9, 30, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	resultsFile := filepath.Join(root, "results.csv")
+	f, err := os.Create(resultsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"MAPPING_ROW_INDEX", "COLLECTION_NAME", "STATUS", "VISUAL_URL", "ONS_URL", "ERROR"})
+	w.Write([]string{"2", promotedCollection, "SUCCESS", "https://visual.ons.gov.uk/promoted/", promotedURI, "N/A"})
+	w.Flush()
+	return resultsFile
+}
+
+func TestRunPromotedCollection(t *testing.T) {
+	root, err := ioutil.TempDir("", "linkcheck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	resultsFile := promotedResults(t, root)
+	if _, err := os.Stat(filepath.Join(root, promotedCollection, "inprogress", promotedURI)); !os.IsNotExist(err) {
+		t.Fatalf("expected the article to have been moved out of inprogress")
+	}
+
+	outputFile := filepath.Join(root, "links.csv")
+	c := New(&migration.Plan{Mapping: &migration.Mapping{}, Archive: &migration.Archive{}}, "")
+	if err := c.Run(resultsFile, outputFile, true); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"2", promotedCollection, promotedURI, RelatedLink, External, "https://www.example.com/report", StatusOK, ""}
+	if len(rows) != 2 {
+		t.Fatalf("expected the header and one link but was %q", rows)
+	}
+	for i, value := range expected {
+		if rows[1][i] != value {
+			t.Errorf("expected %q but was %q", expected, rows[1])
+			break
+		}
+	}
+}
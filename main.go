@@ -1,47 +1,247 @@
 package main
 
 import (
-	"github.com/ONSdigital/go-ns/log"
-	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"flag"
+	"fmt"
 	"os"
-	"github.com/pkg/errors"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
 	"github.com/ONSdigital/dp-visual-ons-migration/config"
-	"flag"
-	"github.com/ONSdigital/dp-visual-ons-migration/migration"
 	"github.com/ONSdigital/dp-visual-ons-migration/executor"
-	"fmt"
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/dp-visual-ons-migration/schema"
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+)
+
+var (
+	// interrupted is closed when a run holding the collections lock is interrupted by a signal
+	interrupted = make(chan struct{})
+
+	// commands run by name e.g. migrator migrate -rows=51-100
+	commands = map[string]func(args []string){
+		"migrate":   migrate,
+		"validate":  validate,
+		"verify":    verify,
+		"promote":   promote,
+		"analyse":   analyseExport,
+		"linkcheck": linkCheck,
+		"inspect":   inspectPost,
+		"serve":     serve,
+		"sync":      syncExport,
+	}
 )
 
 func main() {
 	log.HumanReadable = true
 	log.Info("dp-visual-migration", nil)
 
-	cfgFile := flag.String("cfg", "config.yml", "the config to use when running the migration")
-	startIndex := flag.Int("start", 0, "")
-	batchSize := flag.Int("batchSize", 1, "")
-	flag.Parse()
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+	usage()
+}
 
-	cfg, err := config.Load(*cfgFile)
-	if err != nil {
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands: %s\n\nrun %s <command> -h for the flags of a command\n", os.Args[0], strings.Join(names, ", "), os.Args[0])
+	os.Exit(2)
+}
+
+// migrate migrates the selected mapping rows into collections.
+func migrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	loadConfig := configFlags(flags, "the config to use when running the migration")
+	selection := selectionFlags(flags)
+	forceUnlock := flags.Bool("force-unlock", false, "remove the collections directory lock left by a run that has ended")
+	flags.Parse(args)
+
+	cfg, plan := loadConfig()
+	if err := cfg.ValidateCollectionsDir(); err != nil {
+		exit(errors.Wrap(err, "failed loading config"))
+	}
+	if err := cfg.ValidateResultsPath(); err != nil {
 		exit(errors.Wrap(err, "failed loading config"))
 	}
 
-	//log.Info("configuring collections root directory", log.Data{"dir": cfg.CollectionsDir})
-	zebedee.CollectionsRoot = cfg.CollectionsDir
+	batch, err := selection(plan)
+	if err != nil {
+		exit(err)
+	}
 
-	plan, err := migration.LoadPlan(cfg)
+	grouping, err := executor.NewGrouping(cfg)
+	if err != nil {
+		exit(err)
+	}
+
+	schedule, err := executor.NewSchedule(cfg, time.Now())
 	if err != nil {
 		exit(err)
 	}
 
-	outputFile := fmt.Sprintf(cfg.ResultsFilePath, *startIndex + 2, *startIndex + *batchSize + 1)
-	e, err := executor.New(plan, *startIndex, outputFile)
+	resultsPath := migration.ResultsPath(cfg.ResultsFilePath, batch)
+	lock := lockCollections("migrate", migration.DescribeRows(batch), resultsPath, *forceUnlock)
+	defer lock.Release()
+
+	e, err := executor.New(cfg, plan, grouping, schedule, resultsPath)
 	if err != nil {
+		lock.Release()
 		exit(err)
 	}
 	defer e.Close()
+	stopOnInterrupt(e)
+
+	e.Migrate(batch)
+}
 
-	e.Migrate(*startIndex, *batchSize)
+// lockCollections locks the collections directory for the run, exiting if another run holds the lock. If force is true
+// a lock left by an earlier run is removed first. If the run is interrupted the interrupted channel is closed, the run
+// stops once the work in progress is done so its results are written and the lock released. A second interrupt exits
+// immediately, leaving the lock to be removed with -force-unlock.
+func lockCollections(command string, rows string, resultsPath string, force bool) *zebedee.Lock {
+	if force {
+		if err := zebedee.ForceUnlock(); err != nil {
+			exit(err)
+		}
+	}
+
+	lock, err := zebedee.AcquireLock(command, rows, resultsPath)
+	if err != nil {
+		exit(err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		log.Info("run interrupted, stopping once the work in progress is done", log.Data{"signal": sig.String()})
+		close(interrupted)
+	}()
+	return lock
+}
+
+// stopOnInterrupt stops the executor when the run is interrupted.
+func stopOnInterrupt(e *executor.Executor) {
+	go func() {
+		<-interrupted
+		e.Stop()
+	}()
+}
+
+// isInterrupted returns true if the run has been interrupted.
+func isInterrupted() bool {
+	select {
+	case <-interrupted:
+		return true
+	default:
+		return false
+	}
+}
+
+// selectionFlags adds the flags selecting mapping rows to the flag set, returning a function that selects the rows
+// from the plan once the flags are parsed.
+func selectionFlags(flags *flag.FlagSet) func(plan *migration.Plan) ([]*migration.Article, error) {
+	rows := flags.String("rows", "", "the spreadsheet rows to select e.g. 51-100,120 - the first mapping row is 2")
+	urlList := flags.String("urls", "", "a file listing the visual urls to select, one per line")
+	taxonomy := flags.String("taxonomy", "", "select rows with a taxonomy uri starting with the prefix e.g. /economy")
+	title := flags.String("title", "", "select rows with a post title matching the regular expression")
+	from := flags.String("from", "", "select posts published on or after the date (YYYY-MM-DD, UK time)")
+	to := flags.String("to", "", "select posts published on or before the date (YYYY-MM-DD, UK time)")
+
+	return func(plan *migration.Plan) ([]*migration.Article, error) {
+		s := &migration.Selection{TaxonomyPrefix: *taxonomy}
+		var err error
+
+		if s.Rows, err = migration.ParseRows(*rows); err != nil {
+			return nil, err
+		}
+		if *urlList != "" {
+			if s.URLs, err = migration.ReadURLList(*urlList); err != nil {
+				return nil, err
+			}
+		}
+		if *title != "" {
+			if s.Title, err = regexp.Compile(*title); err != nil {
+				return nil, migration.Error{Message: "invalid title regular expression", OriginalErr: err, Params: log.Data{"title": *title}}
+			}
+		}
+		if *from != "" {
+			if s.PublishedFrom, err = migration.ParseSelectionDate(*from, false); err != nil {
+				return nil, err
+			}
+		}
+		if *to != "" {
+			if s.PublishedTo, err = migration.ParseSelectionDate(*to, true); err != nil {
+				return nil, err
+			}
+		}
+		return s.Select(plan)
+	}
+}
+
+// configFlags adds the flags choosing the config to the flag set, returning a function that loads the config and
+// migration plan once the flags are parsed.
+func configFlags(flags *flag.FlagSet, usage string) func() (*config.Model, *migration.Plan) {
+	cfgFile := flags.String("cfg", "config.yml", usage)
+	profile := flags.String("profile", "", "the config profile to use e.g. local, sandbox or prod - defaults to $"+config.ProfileEnv)
+	overrides := &config.Overrides{}
+	flags.Var(overrides, "set", "override a config setting e.g. -set collections-dir=/tmp/collections, may be repeated")
+
+	return func() (*config.Model, *migration.Plan) {
+		return load(*cfgFile, *profile, *overrides)
+	}
+}
+
+// load the config and migration plan, configuring the zebedee package from the config.
+func load(cfgFile string, profile string, overrides config.Overrides) (*config.Model, *migration.Plan) {
+	cfg, err := config.Load(cfgFile, profile, overrides)
+	if err != nil {
+		exit(errors.Wrap(err, "failed loading config"))
+	}
+
+	//log.Info("configuring collections root directory", log.Data{"dir": cfg.CollectionsDir})
+	zebedee.CollectionsRoot = cfg.CollectionsDir
+	zebedee.PublishedRoot = cfg.PublishedContentDir
+	zebedee.CompletedBy, zebedee.ReviewedBy = cfg.CompletedBy, cfg.ReviewedBy
+	if cfg.VersionNotice != "" {
+		zebedee.VersionNotice = cfg.VersionNotice
+	}
+	if cfg.ReleaseDateSource != "" {
+		zebedee.ReleaseDateSource = cfg.ReleaseDateSource
+	}
+	if cfg.DeterministicIDs {
+		zebedee.SetCollectionIDNamespace(cfg.CollectionIDNamespace)
+	}
+	if cfg.RunTime != "" {
+		// the run time is validated when the config is loaded
+		zebedee.RunTime, _ = time.Parse(time.RFC3339, cfg.RunTime)
+	}
+	if zebedee.Schemas, err = schema.LoadDir(cfg.SchemaDir); err != nil {
+		exit(err)
+	}
+
+	log.Info("config loaded", log.Data{"file": cfgFile, "profile": cfg.Profile})
+
+	plan, err := migration.LoadPlan(cfg)
+	if err != nil {
+		exit(err)
+	}
+	return cfg, plan
 }
 
 func exit(err error) {
@@ -0,0 +1,151 @@
+package migration
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	cdxTimestampFormat = "20060102150405"
+	cdxHeaderPrefix    = "CDX"
+)
+
+var (
+	// a national archives url ending with a capture timestamp e.g. http://webarchive.nationalarchives.gov.uk/20170726163612/
+	archiveURLRX = regexp.MustCompile(`^(.*/)(\d{14})/$`)
+)
+
+// Archive resolves visual URLs that are not migrated to their UK Government Web Archive capture. If a CDX index of the
+// captures is available the closest capture at or before the cut-over is used for each URL, otherwise all URLs use the
+// default snapshot.
+type Archive struct {
+	defaultURL string
+	baseURL    string
+	cutover    string
+	captures   map[string][]string
+	missing    map[string]bool
+	mutex      sync.Mutex
+}
+
+// NewArchive creates an Archive that resolves every URL to the default snapshot.
+func NewArchive(defaultURL string) *Archive {
+	return &Archive{
+		defaultURL: defaultURL,
+		captures:   make(map[string][]string),
+		missing:    make(map[string]bool),
+	}
+}
+
+// LoadArchive creates an Archive from the captures in a local CDX index file. Only successful captures (2xx and 3xx)
+// at or before the cut-over are used.
+func LoadArchive(defaultURL string, cdxFile string, cutover time.Time) (*Archive, error) {
+	a := NewArchive(defaultURL)
+
+	match := archiveURLRX.FindStringSubmatch(defaultURL)
+	if match == nil {
+		return nil, Error{"national archives url must end with a capture timestamp to use a CDX index", nil, log.Data{"url": defaultURL}}
+	}
+	a.baseURL = match[1]
+	a.cutover = cutover.UTC().Format(cdxTimestampFormat)
+
+	f, err := os.Open(cdxFile)
+	if err != nil {
+		return nil, Error{"error while attempting to open CDX index", err, log.Data{"filename": cdxFile}}
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, cdxHeaderPrefix) {
+			continue
+		}
+
+		// urlkey timestamp original mimetype statuscode digest ...
+		fields := strings.Fields(line)
+		if len(fields) < 3 || len(fields[1]) != len(cdxTimestampFormat) {
+			continue
+		}
+
+		if len(fields) > 4 && fields[4] != "-" && !strings.HasPrefix(fields[4], "2") && !strings.HasPrefix(fields[4], "3") {
+			continue
+		}
+
+		key := NormaliseURL(fields[2])
+		a.captures[key] = append(a.captures[key], fields[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, Error{"error while reading CDX index", err, log.Data{"filename": cdxFile}}
+	}
+
+	for _, timestamps := range a.captures {
+		sort.Strings(timestamps)
+	}
+
+	log.Info("loaded national archives CDX index", log.Data{"urls": len(a.captures), "cutover": a.cutover})
+	return a, nil
+}
+
+// GetArchivedURL returns the national archives URL of the closest capture of the visual URL at or before the cut-over,
+// falling back to the default snapshot.
+func (a *Archive) GetArchivedURL(current string) string {
+	if timestamp, ok := a.GetCapture(current); ok {
+		return a.baseURL + timestamp + "/" + current
+	}
+	return a.defaultURL + current
+}
+
+// GetCapture returns the timestamp of the closest capture of the URL at or before the cut-over. URLs without any
+// captures are recorded and returned by Missing.
+func (a *Archive) GetCapture(current string) (string, bool) {
+	if a.baseURL == "" {
+		return "", false
+	}
+
+	timestamps, ok := a.captures[NormaliseURL(current)]
+	if !ok {
+		a.mutex.Lock()
+		a.missing[current] = true
+		a.mutex.Unlock()
+		return "", false
+	}
+
+	// the index of the first capture after the cut-over
+	i := sort.Search(len(timestamps), func(i int) bool { return timestamps[i] > a.cutover })
+	if i == 0 {
+		return "", false
+	}
+	return timestamps[i-1], true
+}
+
+// HasCaptures returns true if the CDX index has any capture of the URL.
+func (a *Archive) HasCaptures(current string) bool {
+	_, ok := a.captures[NormaliseURL(current)]
+	return ok
+}
+
+// HasIndex returns true if the archive was loaded from a CDX index.
+func (a *Archive) HasIndex() bool {
+	return a.baseURL != ""
+}
+
+// Missing returns the URLs resolved so far that have no capture in the CDX index.
+func (a *Archive) Missing() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	urls := make([]string, 0, len(a.missing))
+	for u := range a.missing {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls
+}
@@ -0,0 +1,81 @@
+package migration
+
+import (
+	"time"
+
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	// PublishedDate use the date the visual post was first published.
+	PublishedDate = "published"
+	// ModifiedDate use the date the visual post was last modified, falling back to the published date.
+	ModifiedDate = "modified"
+
+	wpDateFormat   = "2006-01-02 15:04:05"
+	wpEmptyDate    = "0000-00-00 00:00:00"
+	wpSiteTimezone = "Europe/London"
+)
+
+// GetPostDate returns the date of a visual post in UTC. WordPress records each date twice, in GMT (post_date_gmt) and
+// in the site's local time (post_date) - the GMT value is used when it is set as it is unaffected by British Summer
+// Time, otherwise the local value is converted from UK time. The RSS pubDate is the last resort.
+func GetPostDate(i *gofeed.Item, source string) (time.Time, error) {
+	switch source {
+	case "", PublishedDate:
+		return getPostDate(i, "post_date")
+	case ModifiedDate:
+		if t, ok, err := getWPDate(i, "post_modified"); ok || err != nil {
+			return t, err
+		}
+		log.Debug("visual post has no modified date, using published date", log.Data{"title": i.Title})
+		return getPostDate(i, "post_date")
+	default:
+		return time.Time{}, Error{"unknown release date source", nil, log.Data{"source": source}}
+	}
+}
+
+func getPostDate(i *gofeed.Item, field string) (time.Time, error) {
+	if t, ok, err := getWPDate(i, field); ok || err != nil {
+		return t, err
+	}
+
+	if i.PublishedParsed != nil {
+		return i.PublishedParsed.UTC(), nil
+	}
+	return time.Time{}, Error{"visual post has no publish date", nil, log.Data{"title": i.Title, "url": i.Link}}
+}
+
+// getWPDate reads a wp date field, returning false if neither the GMT or local value is set.
+func getWPDate(i *gofeed.Item, field string) (time.Time, bool, error) {
+	if value := GetWPValue(i, field+"_gmt"); value != "" && value != wpEmptyDate {
+		t, err := time.Parse(wpDateFormat, value)
+		if err != nil {
+			return time.Time{}, false, Error{"failed to parse visual post date", err, log.Data{"title": i.Title, "field": field + "_gmt", "value": value}}
+		}
+		return t.UTC(), true, nil
+	}
+
+	if value := GetWPValue(i, field); value != "" && value != wpEmptyDate {
+		loc, err := time.LoadLocation(wpSiteTimezone)
+		if err != nil {
+			return time.Time{}, false, Error{"failed to load timezone", err, log.Data{"timezone": wpSiteTimezone}}
+		}
+
+		t, err := time.ParseInLocation(wpDateFormat, value, loc)
+		if err != nil {
+			return time.Time{}, false, Error{"failed to parse visual post date", err, log.Data{"title": i.Title, "field": field, "value": value}}
+		}
+		return t.UTC(), true, nil
+	}
+	return time.Time{}, false, nil
+}
+
+// GetWPValue returns the value of the first wp extension element with the given name or an empty string.
+func GetWPValue(i *gofeed.Item, name string) string {
+	if values := i.Extensions["wp"][name]; len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package migration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/extensions"
+)
+
+// newPost returns a visual post with the wp fields and RSS pubDate, an empty pubDate is not set.
+func newPost(fields map[string]string, pubDate string) *gofeed.Item {
+	wp := make(map[string][]ext.Extension)
+	for name, value := range fields {
+		wp[name] = []ext.Extension{{Name: name, Value: value}}
+	}
+
+	i := &gofeed.Item{Title: "post", Extensions: ext.Extensions{"wp": wp}}
+	if pubDate != "" {
+		t, err := time.Parse(time.RFC1123Z, pubDate)
+		if err != nil {
+			panic(err)
+		}
+		i.PublishedParsed = &t
+	}
+	return i
+}
+
+func utc(value string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// British Summer Time started at 2017-03-26 01:00 UTC and ended at 2017-10-29 01:00 UTC.
+func TestGetPostDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   map[string]string
+		pubDate  string
+		source   string
+		expected time.Time
+	}{
+		{
+			name:     "post_date_gmt before BST starts",
+			fields:   map[string]string{"post_date_gmt": "2017-03-26 00:30:00", "post_date": "2017-03-26 00:30:00"},
+			expected: utc("2017-03-26 00:30:00"),
+		},
+		{
+			name:     "post_date_gmt after BST starts is used over post_date",
+			fields:   map[string]string{"post_date_gmt": "2017-03-26 01:30:00", "post_date": "2017-03-26 02:30:00"},
+			expected: utc("2017-03-26 01:30:00"),
+		},
+		{
+			name:     "post_date_gmt before BST ends",
+			fields:   map[string]string{"post_date_gmt": "2017-10-29 00:30:00", "post_date": "2017-10-29 01:30:00"},
+			expected: utc("2017-10-29 00:30:00"),
+		},
+		{
+			name:     "post_date_gmt after BST ends",
+			fields:   map[string]string{"post_date_gmt": "2017-10-29 01:30:00", "post_date": "2017-10-29 01:30:00"},
+			expected: utc("2017-10-29 01:30:00"),
+		},
+		{
+			name:     "post_date only before BST starts is GMT",
+			fields:   map[string]string{"post_date": "2017-03-26 00:30:00"},
+			expected: utc("2017-03-26 00:30:00"),
+		},
+		{
+			name:     "post_date only after BST starts is an hour ahead of UTC",
+			fields:   map[string]string{"post_date": "2017-03-26 02:30:00"},
+			expected: utc("2017-03-26 01:30:00"),
+		},
+		{
+			name:     "post_date only before BST ends is an hour ahead of UTC",
+			fields:   map[string]string{"post_date": "2017-10-29 00:30:00"},
+			expected: utc("2017-10-28 23:30:00"),
+		},
+		{
+			name:     "post_date only after BST ends is GMT",
+			fields:   map[string]string{"post_date": "2017-10-29 02:30:00"},
+			expected: utc("2017-10-29 02:30:00"),
+		},
+		{
+			name:     "empty post_date_gmt falls back to post_date",
+			fields:   map[string]string{"post_date_gmt": wpEmptyDate, "post_date": "2017-03-26 02:30:00"},
+			expected: utc("2017-03-26 01:30:00"),
+		},
+		{
+			name:     "pubDate fallback before BST starts",
+			fields:   map[string]string{"post_date_gmt": wpEmptyDate, "post_date": wpEmptyDate},
+			pubDate:  "Sun, 26 Mar 2017 00:30:00 +0000",
+			expected: utc("2017-03-26 00:30:00"),
+		},
+		{
+			name:     "pubDate fallback after BST starts",
+			fields:   map[string]string{},
+			pubDate:  "Sun, 26 Mar 2017 02:30:00 +0100",
+			expected: utc("2017-03-26 01:30:00"),
+		},
+		{
+			name:     "pubDate fallback before BST ends",
+			fields:   map[string]string{},
+			pubDate:  "Sun, 29 Oct 2017 01:30:00 +0100",
+			expected: utc("2017-10-29 00:30:00"),
+		},
+		{
+			name:     "pubDate fallback after BST ends",
+			fields:   map[string]string{},
+			pubDate:  "Sun, 29 Oct 2017 01:30:00 +0000",
+			expected: utc("2017-10-29 01:30:00"),
+		},
+		{
+			name:     "modified date",
+			fields:   map[string]string{"post_date_gmt": "2017-03-26 00:30:00", "post_modified": "2017-10-29 00:30:00"},
+			source:   ModifiedDate,
+			expected: utc("2017-10-28 23:30:00"),
+		},
+		{
+			name:     "modified date falls back to the published date",
+			fields:   map[string]string{"post_date_gmt": "2017-03-26 01:30:00", "post_modified_gmt": wpEmptyDate},
+			source:   ModifiedDate,
+			expected: utc("2017-03-26 01:30:00"),
+		},
+	}
+
+	for _, test := range tests {
+		actual, err := GetPostDate(newPost(test.fields, test.pubDate), test.source)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", test.name, err)
+			continue
+		}
+		if !actual.Equal(test.expected) || actual.Location() != time.UTC {
+			t.Errorf("%s: expected %s but was %s", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestGetPostDateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		source string
+	}{
+		{name: "no date", fields: map[string]string{"post_date": wpEmptyDate}},
+		{name: "invalid post_date_gmt", fields: map[string]string{"post_date_gmt": "26/03/2017 01:30"}},
+		{name: "invalid post_date", fields: map[string]string{"post_date": "26/03/2017 01:30"}},
+		{name: "unknown source", fields: map[string]string{"post_date": "2017-03-26 01:30:00"}, source: "created"},
+	}
+
+	for _, test := range tests {
+		if _, err := GetPostDate(newPost(test.fields, ""), test.source); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
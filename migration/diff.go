@@ -0,0 +1,122 @@
+package migration
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	PostAdded     = "added"
+	PostChanged   = "changed"
+	PostDeleted   = "deleted"
+	PostUnchanged = "unchanged"
+
+	postIDField       = "post_id"
+	postModifiedField = "post_modified_gmt"
+)
+
+// PostChange the difference of a visual post between two exports.
+type PostChange struct {
+	ID     string
+	URL    string
+	Title  string
+	Change string
+	// the post in the newer export, nil if it was deleted
+	Post *gofeed.Item
+}
+
+// LoadVisualExport parses a wordpress export without a migration mapping.
+func LoadVisualExport(filename string) (*VisualExport, error) {
+	m := &Mapping{ToMigrate: []*Article{}}
+	m.index()
+
+	export, err := parseVisualExport(filename, m, PublishedDate)
+	if err != nil {
+		return nil, Error{"failed to load visual export", err, log.Data{"filename": filename}}
+	}
+	return export, nil
+}
+
+// GetPostID returns the wordpress post id of the visual post.
+func GetPostID(i *gofeed.Item) string {
+	return GetWPValue(i, postIDField)
+}
+
+// GetPostVersion identifies the revision of the visual post. The modified date is used if the export includes it,
+// otherwise a hash of the title, content, excerpt, categories and metadata.
+func GetPostVersion(i *gofeed.Item) string {
+	if modified := GetWPValue(i, postModifiedField); modified != "" && modified != wpEmptyDate {
+		return modified
+	}
+
+	h := sha1.New()
+	write := func(values ...string) {
+		for _, v := range values {
+			h.Write([]byte(v))
+			h.Write([]byte{0})
+		}
+	}
+
+	write(i.Title, i.Link)
+	for _, ext := range []string{"content", "excerpt"} {
+		for _, v := range i.Extensions[ext]["encoded"] {
+			write(v.Value)
+		}
+	}
+
+	domains := make([]string, 0, len(i.Extensions[categoryExt]))
+	for domain := range i.Extensions[categoryExt] {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	for _, domain := range domains {
+		write(domain)
+		write(GetCategories(i, domain)...)
+	}
+
+	meta := make([]string, 0)
+	for _, m := range i.Extensions["wp"]["postmeta"] {
+		meta = append(meta, m.Children["meta_key"][0].Value+"="+m.Children["meta_value"][0].Value)
+	}
+	sort.Strings(meta)
+	write(meta...)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DiffExports compares the posts of two exports by wordpress post id, returning the changes ordered by post url.
+func DiffExports(older *VisualExport, newer *VisualExport) []*PostChange {
+	changes := make([]*PostChange, 0)
+
+	for _, post := range newer.Posts {
+		id := GetPostID(post)
+		c := &PostChange{ID: id, URL: post.Link, Title: post.Title, Post: post}
+
+		previous, ok := older.GetPostByID(id)
+		switch {
+		case !ok:
+			c.Change = PostAdded
+		case GetPostVersion(previous) != GetPostVersion(post):
+			c.Change = PostChanged
+		default:
+			c.Change = PostUnchanged
+		}
+		changes = append(changes, c)
+	}
+
+	for _, post := range older.Posts {
+		if _, ok := newer.GetPostByID(GetPostID(post)); !ok {
+			changes = append(changes, &PostChange{ID: GetPostID(post), URL: post.Link, Title: post.Title, Change: PostDeleted})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return strings.ToLower(changes[i].URL) < strings.ToLower(changes[j].URL)
+	})
+	return changes
+}
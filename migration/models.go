@@ -1,11 +1,12 @@
 package migration
 
 import (
+	"github.com/ONSdigital/dp-visual-ons-migration/config"
 	"github.com/ONSdigital/go-ns/log"
-	"regexp"
-	"strings"
 	"github.com/mmcdole/gofeed"
 	"net/url"
+	"regexp"
+	"strings"
 )
 
 type Error struct {
@@ -16,11 +17,17 @@ type Error struct {
 
 // Article a florence article
 type Article struct {
-	PostTitle    string   `json:"postTitle"`
-	TaxonomyURI  string   `json:"taxonomyURI"`
-	RelatedLinks []string `json:"links"`
-	Keywords     []string `json:"keywords"`
-	VisualURL    string   `json:"visualURL"`
+	Row          int               `json:"row"`
+	PostTitle    string            `json:"postTitle"`
+	TaxonomyURI  string            `json:"taxonomyURI"`
+	RelatedLinks []string          `json:"links"`
+	Keywords     []string          `json:"keywords"`
+	VisualURL    string            `json:"visualURL"`
+	Fields       map[string]string `json:"fields"`
+	// PageType the ONS page type the post is migrated to, one of PageTypes.
+	PageType string `json:"pageType"`
+	// Compendium the title of the compendium a compendium chapter belongs to.
+	Compendium string `json:"compendium,omitempty"`
 }
 
 // Top level structure holding all the migration details.
@@ -28,12 +35,21 @@ type Plan struct {
 	VisualExport        *VisualExport
 	Mapping             *Mapping
 	NationalArchivesURL string
+	Archive             *Archive
+	Contacts            *config.Contacts
+	Vocabulary          *config.Vocabulary
+	Sites               config.Sites
+	// Welsh the Welsh translations of the visual posts, nil if none are configured.
+	Welsh *Translations
 }
 
 // mapping of the posts to migrate - from -> to.
 type Mapping struct {
+	Header        []string
 	ToMigrate     []*Article
 	NotToMigrated map[string]*Article
+	byURL         map[string]*Article
+	byURI         map[string]*Article
 }
 
 type Attachment struct {
@@ -45,22 +61,64 @@ type Attachment struct {
 type VisualExport struct {
 	Attachments map[string]*Attachment
 	Posts       map[string]*gofeed.Item
+
+	// indexes by normalised url and wordpress post id
+	attachmentsByURL map[string]*Attachment
+	attachmentsByID  map[string]*Attachment
+	postsByURL       map[string]*gofeed.Item
+	postsByID        map[string]*gofeed.Item
 }
 
 func newVisualExport() *VisualExport {
 	return &VisualExport{
-		Attachments: make(map[string]*Attachment),
-		Posts:       make(map[string]*gofeed.Item),
+		Attachments:      make(map[string]*Attachment),
+		Posts:            make(map[string]*gofeed.Item),
+		attachmentsByURL: make(map[string]*Attachment),
+		attachmentsByID:  make(map[string]*Attachment),
+		postsByURL:       make(map[string]*gofeed.Item),
+		postsByID:        make(map[string]*gofeed.Item),
 	}
 }
 
-func (m *Mapping) GetArticleByURL(target string) (*Article, bool) {
+// index the mapping entries by normalised visual url, if a url is mapped more than once the first entry is used.
+func (m *Mapping) index() {
+	m.byURL = make(map[string]*Article, len(m.ToMigrate))
+	m.byURI = make(map[string]*Article, len(m.ToMigrate))
 	for _, a := range m.ToMigrate {
-		if target == a.VisualURL {
-			return a, true
+		key := NormaliseURL(a.VisualURL)
+		if _, ok := m.byURL[key]; !ok {
+			m.byURL[key] = a
 		}
 	}
-	return nil, false
+}
+
+// GetArticleByURI returns the mapping entry that will be migrated to the ONS URI.
+func (m *Mapping) GetArticleByURI(uri string) (*Article, bool) {
+	a, ok := m.byURI[strings.TrimSuffix(uri, "/")]
+	return a, ok
+}
+
+func (m *Mapping) GetArticleByURL(target string) (*Article, bool) {
+	a, ok := m.byURL[NormaliseURL(target)]
+	return a, ok
+}
+
+// GetPost returns the visual post with the given url.
+func (m *VisualExport) GetPost(target string) (*gofeed.Item, bool) {
+	i, ok := m.postsByURL[NormaliseURL(target)]
+	return i, ok
+}
+
+// GetPostByID returns the visual post with the given wordpress post id.
+func (m *VisualExport) GetPostByID(id string) (*gofeed.Item, bool) {
+	i, ok := m.postsByID[id]
+	return i, ok
+}
+
+// GetAttachment returns the visual attachment with the given url.
+func (m *VisualExport) GetAttachment(target string) (*Attachment, bool) {
+	a, ok := m.attachmentsByURL[NormaliseURL(target)]
+	return a, ok
 }
 
 func (p *Plan) GetMigratedURL(current string) (string, error) {
@@ -70,22 +128,22 @@ func (p *Plan) GetMigratedURL(current string) (string, error) {
 	}
 	data := log.Data{"url": current}
 
-	if currentURL.Host == "visual.ons.gov.uk" {
+	if strings.EqualFold(currentURL.Host, p.Sites.VisualHost) {
 
 		// check if the url is a migrated visual attachment - if so return the url for its migrated location.
-		if attachment, ok := p.VisualExport.Attachments[current]; ok {
+		if attachment, ok := p.VisualExport.GetAttachment(current); ok {
 			log.Debug("visual attachment url found", data)
-			return staticONSHost + strings.Replace(attachment.URL.Path, wpAttachmentPath, staticONSPath, 1), nil
+			return p.Sites.StaticONSSite + strings.Replace(attachment.URL.Path, p.Sites.VisualUploadsPath, p.Sites.StaticONSPath, 1), nil
 		}
 
 		// otherwise check if the url is a migrated visual post then return the URL of where the post will be migrated to
 		if migrationPost, ok := p.Mapping.GetArticleByURL(current); ok {
 			log.Debug("visual migration post url found", data)
-			return ONSSite + migrationPost.TaxonomyURI, nil
+			return p.Sites.ONSSite + migrationPost.TaxonomyURI, nil
 		}
 
 		log.Debug("visual url found but not attachment or migration post", data)
-		return p.NationalArchivesURL + current, nil
+		return p.Archive.GetArchivedURL(current), nil
 	}
 
 	// its not a visual post or attachment - so no transformation required nothing.
@@ -100,7 +158,10 @@ func (m *VisualExport) addAttachment(i *gofeed.Item) error {
 	}
 
 	postID := i.Extensions["wp"]["post_id"][0].Value
-	m.Attachments[attachmentURL.String()] = &Attachment{URL: attachmentURL, Title: i.Title, ID: postID}
+	attachment := &Attachment{URL: attachmentURL, Title: i.Title, ID: postID}
+	m.Attachments[attachmentURL.String()] = attachment
+	m.attachmentsByURL[NormaliseURL(attachmentURL.String())] = attachment
+	m.attachmentsByID[postID] = attachment
 	return nil
 }
 
@@ -113,6 +174,8 @@ func (m *VisualExport) addPost(i *gofeed.Item) error {
 
 	if _, ok := m.Posts[postURL.String()]; !ok {
 		m.Posts[postURL.String()] = i
+		m.postsByURL[NormaliseURL(postURL.String())] = i
+		m.postsByID[GetPostID(i)] = i
 	} else {
 		return Error{"duplicate entry in visual RSS xmL", err, log.Data{"title": i.Title, "url": i.Link}}
 	}
@@ -120,11 +183,8 @@ func (m *VisualExport) addPost(i *gofeed.Item) error {
 }
 
 func (m *VisualExport) GetThumbnailURL(thumbnailID string) *url.URL {
-
-	for _, attachment := range m.Attachments {
-		if attachment.ID == thumbnailID {
-			return attachment.URL
-		}
+	if attachment, ok := m.attachmentsByID[thumbnailID]; ok {
+		return attachment.URL
 	}
 	return nil
 }
@@ -153,6 +213,11 @@ func (e Error) Error() string {
 	return msg
 }
 
+// GetTaxonomyRoot returns the top level taxonomy node the article was mapped to e.g. "economy".
+func (a *Article) GetTaxonomyRoot() string {
+	return strings.Split(strings.Trim(a.TaxonomyURI, "/"), "/")[0]
+}
+
 func (m *Article) GetTaxonomyURI() string {
 	return m.TaxonomyURI + "/" + strings.TrimSpace(strings.ToLower(m.GetCollectionName()))
 }
@@ -0,0 +1,76 @@
+package migration
+
+import (
+	"fmt"
+	"testing"
+)
+
+const syntheticPosts = 50000
+
+// syntheticPlan returns a plan of a generated export with n posts, each mapped and with a thumbnail attachment. The
+// export and mapping are built through the same functions as the parsers so the lookups use the real indexes.
+func syntheticPlan(n int) *Plan {
+	m := &Mapping{ToMigrate: make([]*Article, 0, n)}
+	export := newVisualExport()
+
+	for i := 0; i < n; i++ {
+		postURL := fmt.Sprintf("https://visual.ons.gov.uk/synthetic-post-%d/", i)
+		m.ToMigrate = append(m.ToMigrate, &Article{
+			Row:         i + FirstMappingRow,
+			PostTitle:   fmt.Sprintf("Synthetic post %d", i),
+			TaxonomyURI: fmt.Sprintf("/economy/synthetic/articles/syntheticpost%d/2017-01-01", i),
+			VisualURL:   postURL,
+			Fields:      map[string]string{},
+		})
+
+		post := newPost(map[string]string{"post_type": postType, postIDField: fmt.Sprintf("post-%d", i)}, "")
+		post.Link = postURL
+		if err := export.addPost(post); err != nil {
+			panic(err)
+		}
+
+		attachment := newPost(map[string]string{
+			"post_type":      attachmentType,
+			postIDField:      fmt.Sprintf("%d", i),
+			"attachment_url": fmt.Sprintf("https://visual.ons.gov.uk/wp-content/uploads/2017/01/thumbnail-%d.png", i),
+		}, "")
+		if err := export.addAttachment(attachment); err != nil {
+			panic(err)
+		}
+	}
+	m.index()
+
+	return &Plan{Mapping: m, VisualExport: export}
+}
+
+func BenchmarkGetArticleByURL(b *testing.B) {
+	plan := syntheticPlan(syntheticPosts)
+	urls := make([]string, 0, syntheticPosts)
+	for _, a := range plan.Mapping.ToMigrate {
+		// lookups are by the urls found in post bodies, which differ from the mapping in scheme and trailing slash
+		urls = append(urls, "http://VISUAL.ons.gov.uk"+a.VisualURL[len("https://visual.ons.gov.uk"):len(a.VisualURL)-1])
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := plan.Mapping.GetArticleByURL(urls[i%len(urls)]); !ok {
+			b.Fatalf("post %s not found", urls[i%len(urls)])
+		}
+	}
+}
+
+func BenchmarkGetThumbnailURL(b *testing.B) {
+	plan := syntheticPlan(syntheticPosts)
+	ids := make([]string, 0, syntheticPosts)
+	for i := 0; i < syntheticPosts; i++ {
+		ids = append(ids, fmt.Sprintf("%d", i))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id := ids[i%len(ids)]
+		if u := plan.VisualExport.GetThumbnailURL(id); u == nil {
+			b.Fatalf("thumbnail %s not found", id)
+		}
+	}
+}
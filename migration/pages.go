@@ -0,0 +1,143 @@
+package migration
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/config"
+	"github.com/ONSdigital/dp-visual-ons-migration/util"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	// the ONS page types a visual post can be migrated to
+	PageArticle           = "article"
+	PageStaticPage        = "static_page"
+	PageStaticArticle     = "static_article"
+	PageCompendiumChapter = "compendium_chapter"
+
+	editionFormat = "2006-01-02"
+)
+
+var (
+	// PageTypes the page types in the form used by zebedee, and the other names accepted in the mapping.
+	PageTypes = []string{PageArticle, PageStaticPage, PageStaticArticle, PageCompendiumChapter}
+
+	pageTypeAliases = map[string]string{
+		"compendium": PageCompendiumChapter,
+	}
+)
+
+// ParsePageType returns the zebedee page type named by the value, ignoring case and accepting spaces or hyphens in
+// place of underscores e.g. "Static page" is static_page.
+func ParsePageType(value string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(value))
+	name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+
+	if alias, ok := pageTypeAliases[name]; ok {
+		return alias, nil
+	}
+	for _, t := range PageTypes {
+		if name == t {
+			return t, nil
+		}
+	}
+	return "", Error{fmt.Sprintf("unknown page type, expected one of %s", strings.Join(PageTypes, ", ")), nil, log.Data{"page_type": value}}
+}
+
+// assignPageTypes sets the page type of each mapping entry from the page-type-column, or the page-type if the column
+// is not configured or is empty. Compendium chapters must name their compendium in the compendium-column.
+func (m *Mapping) assignPageTypes(cfg *config.Model) error {
+	defaultType := PageArticle
+	if cfg.PageType != "" {
+		t, err := ParsePageType(cfg.PageType)
+		if err != nil {
+			return err
+		}
+		defaultType = t
+	}
+
+	for _, a := range m.ToMigrate {
+		a.PageType = defaultType
+		if value := a.Fields[cfg.PageTypeColumn]; cfg.PageTypeColumn != "" && value != "" {
+			t, err := ParsePageType(value)
+			if err != nil {
+				return Error{"invalid page type in mapping", err, log.Data{"row": a.Row, "column": cfg.PageTypeColumn, "page_type": value}}
+			}
+			a.PageType = t
+		}
+
+		if a.PageType == PageCompendiumChapter {
+			if a.Compendium = a.Fields[cfg.CompendiumColumn]; cfg.CompendiumColumn == "" || a.Compendium == "" {
+				return Error{"compendium chapter has no compendium, set compendium-column", nil, log.Data{"row": a.Row, "column": cfg.CompendiumColumn}}
+			}
+		}
+	}
+	return nil
+}
+
+// assignURIs sets the ONS URI of each mapped post from its taxonomy node, page type, title and the post date given by
+// the date source, the same date as the release date of the article:
+//
+//	article            <taxonomy>/articles/<title>/<published date>
+//	static_page        <taxonomy>/<title>
+//	static_article     <taxonomy>/<title>
+//	compendium_chapter <taxonomy>/compendium/<compendium>/<edition>/<title>
+//
+// The edition of a compendium is the date of its earliest chapter in the mapping.
+func (m *Mapping) assignURIs(posts map[*Article]*gofeed.Item, dateSource string) error {
+	dates := make(map[*Article]time.Time, len(posts))
+	editions := make(map[string]time.Time)
+	for a, item := range posts {
+		date, err := GetPostDate(item, dateSource)
+		if err != nil {
+			return Error{"failed to date the ONS uri of mapped post", err, log.Data{"row": a.Row, "url": a.VisualURL}}
+		}
+		dates[a] = date
+
+		if a.PageType != PageCompendiumChapter {
+			continue
+		}
+		key := compendiumURI(a)
+		if edition, ok := editions[key]; !ok || date.Before(edition) {
+			editions[key] = date
+		}
+	}
+
+	for _, a := range m.ToMigrate {
+		item, ok := posts[a]
+		if !ok {
+			continue
+		}
+
+		slug := util.SanitisedFilename(item.Title)
+		switch a.PageType {
+		case PageStaticPage, PageStaticArticle:
+			a.TaxonomyURI = fmt.Sprintf("%s/%s", a.TaxonomyURI, slug)
+		case PageCompendiumChapter:
+			compendium := compendiumURI(a)
+			a.TaxonomyURI = fmt.Sprintf("%s/%s/%s", compendium, editions[compendium].Format(editionFormat), slug)
+		default:
+			a.TaxonomyURI = fmt.Sprintf("%s/articles/%s/%s", a.TaxonomyURI, slug, dates[a].Format(editionFormat))
+		}
+		m.byURI[a.TaxonomyURI] = a
+	}
+	return nil
+}
+
+// LandingPageURI returns the uri of the landing page of the compendium a chapter belongs to - the parent of its ONS
+// uri. It is empty if the article is not a compendium chapter.
+func (a *Article) LandingPageURI() string {
+	if a.PageType != PageCompendiumChapter {
+		return ""
+	}
+	return path.Dir(a.TaxonomyURI)
+}
+
+// compendiumURI returns the uri of the compendium the chapter belongs to, without its edition.
+func compendiumURI(a *Article) string {
+	return fmt.Sprintf("%s/compendium/%s", a.TaxonomyURI, util.SanitisedFilename(a.Compendium))
+}
@@ -8,17 +8,18 @@ import (
 	"strings"
 	"github.com/mmcdole/gofeed"
 	"github.com/ONSdigital/dp-visual-ons-migration/config"
-	"fmt"
-	"github.com/ONSdigital/dp-visual-ons-migration/util"
+	"time"
 )
 
 const (
-	staticONSHost    = "https://static.ons.gov.uk"
-	ONSSite          = "https://www.ons.gov.uk"
-	wpAttachmentPath = "/wp-content/uploads/"
-	staticONSPath    = "/visual/"
-	postType         = "post"
-	attachmentType   = "attachment"
+	postType       = "post"
+	attachmentType = "attachment"
+
+	cutoverDateTimeFormat = "2006-01-02 15:04"
+	cutoverDateFormat     = "2006-01-02"
+
+	// the spreadsheet row of the first mapping entry - rows are numbered from 1 and the first row is the header
+	FirstMappingRow = 2
 )
 
 func LoadPlan(cfg *config.Model) (*Plan, error) {
@@ -28,7 +29,21 @@ func LoadPlan(cfg *config.Model) (*Plan, error) {
 		return nil, err
 	}
 
-	visualExport, err := parseVisualExport(cfg.VisualExportFile, migrationMapping)
+	if err := migrationMapping.assignPageTypes(cfg); err != nil {
+		return nil, err
+	}
+
+	visualExport, err := parseVisualExport(cfg.VisualExportFile, migrationMapping, cfg.ReleaseDateSource)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := loadArchive(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	welsh, err := loadTranslations(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -37,9 +52,39 @@ func LoadPlan(cfg *config.Model) (*Plan, error) {
 		Mapping:             migrationMapping,
 		VisualExport:        visualExport,
 		NationalArchivesURL: cfg.NationalArchivesURL,
+		Archive:             archive,
+		Contacts:            cfg.Contacts,
+		Vocabulary:          cfg.Vocabulary,
+		Sites:               cfg.Sites,
+		Welsh:               welsh,
 	}, nil
 }
 
+// load the national archives CDX index if one is configured.
+func loadArchive(cfg *config.Model) (*Archive, error) {
+	if cfg.NationalArchivesCDXFile == "" {
+		return NewArchive(cfg.NationalArchivesURL), nil
+	}
+
+	cutover := time.Now()
+	if cfg.NationalArchivesCutover != "" {
+		loc, err := time.LoadLocation(wpSiteTimezone)
+		if err != nil {
+			return nil, Error{"failed to load timezone", err, log.Data{"timezone": wpSiteTimezone}}
+		}
+
+		if cutover, err = time.ParseInLocation(cutoverDateTimeFormat, cfg.NationalArchivesCutover, loc); err != nil {
+			if cutover, err = time.ParseInLocation(cutoverDateFormat, cfg.NationalArchivesCutover, loc); err != nil {
+				return nil, Error{"failed to parse national archives cut-over date", err, log.Data{"date": cfg.NationalArchivesCutover}}
+			}
+			// a cut-over date includes captures taken at any time on that day
+			cutover = cutover.AddDate(0, 0, 1).Add(-time.Second)
+		}
+	}
+
+	return LoadArchive(cfg.NationalArchivesURL, cfg.NationalArchivesCDXFile, cutover)
+}
+
 // Parse the mapping file.
 func parseMappingFile(filename string) (*Mapping, error) {
 	f, err := os.Open(filename)
@@ -50,7 +95,7 @@ func parseMappingFile(filename string) (*Mapping, error) {
 	defer f.Close()
 
 	rows := make([][]string, 0)
-	mapping := &Mapping{ToMigrate: make([]*Article, 0)}
+	mapping := &Mapping{Header: make([]string, 0), ToMigrate: make([]*Article, 0)}
 	isHeader := true
 	reader := csv.NewReader(f)
 
@@ -67,6 +112,9 @@ func parseMappingFile(filename string) (*Mapping, error) {
 		}
 
 		if isHeader {
+			for _, name := range row {
+				mapping.Header = append(mapping.Header, strings.TrimSpace(name))
+			}
 			isHeader = false
 			continue
 		}
@@ -74,24 +122,34 @@ func parseMappingFile(filename string) (*Mapping, error) {
 		rows = append(rows, row)
 	}
 
-	for _, line := range rows {
+	for i, line := range rows {
+		fields := make(map[string]string)
+		for col, name := range mapping.Header {
+			if col < len(line) {
+				fields[name] = strings.TrimSpace(line[col])
+			}
+		}
 
 		a := &Article{
+			Row:          i + FirstMappingRow,
 			PostTitle:    strings.TrimSpace(line[0]),
 			TaxonomyURI:  strings.TrimSpace(line[1]),
 			RelatedLinks: []string{},
 			Keywords:     toSlice(line[3], ";"),
 			VisualURL:    strings.TrimSpace(line[4]),
+			Fields:       fields,
 		}
 
 		mapping.ToMigrate = append(mapping.ToMigrate, a)
 	}
 
+	mapping.index()
 	return mapping, nil
 }
 
-// parse the visual ons rss file into the visual export structure
-func parseVisualExport(filename string, m *Mapping) (*VisualExport, error) {
+// parse the visual ons rss file into the visual export structure, dating the URIs of the mapped posts by the date
+// source as their release dates are.
+func parseVisualExport(filename string, m *Mapping, dateSource string) (*VisualExport, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -100,9 +158,11 @@ func parseVisualExport(filename string, m *Mapping) (*VisualExport, error) {
 	defer file.Close()
 
 	vm := newVisualExport()
+	posts := make(map[*Article]*gofeed.Item)
 
 	//log.Info("attempting to parse RSS export file", nil)
 	fp := gofeed.NewParser()
+	fp.RSSTranslator = &visualTranslator{}
 	visualFeed, err := fp.Parse(file)
 	if err != nil {
 		return nil, Error{"failed to parse visual RSS feed", err, nil}
@@ -119,27 +179,23 @@ func parseVisualExport(filename string, m *Mapping) (*VisualExport, error) {
 
 			if a, ok := m.GetArticleByURL(item.Link); ok {
 				//log.Info("adding post to migration mapping", log.Data{"visualURL": item.Link})
-				vm.addPost(item)
-				a.TaxonomyURI = fmt.Sprintf("%s/articles/%s/%s", a.TaxonomyURI, util.SanitisedFilename(item.Title), item.PublishedParsed.Format("2006-01-02"))
+				posts[a] = item
 			}
 		}
 	}
+	if err := m.assignURIs(posts, dateSource); err != nil {
+		return nil, err
+	}
 	//log.Info("mapping generated successfully", nil)
 	return vm, nil
 }
 
 func toSlice(line string, delimiter string) []string {
-	if len(line) == 0 {
-		return nil
-	}
-
-	if !strings.Contains(line, delimiter) {
-		return nil
-	}
-
-	items := strings.SplitN(line, delimiter, -1)
-	for i, val := range items {
-		items[i] = strings.TrimSpace(val)
+	items := make([]string, 0)
+	for _, val := range strings.Split(line, delimiter) {
+		if val = strings.TrimSpace(val); val != "" {
+			items = append(items, val)
+		}
 	}
 	return items
 }
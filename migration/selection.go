@@ -0,0 +1,235 @@
+package migration
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	selectionDateFormat = "2006-01-02"
+	resultsRowsVerb     = "%d-%d"
+
+	// selections with more row ranges than this are named by their first and last row and size
+	maxNamedRanges = 4
+)
+
+// RowRange an inclusive range of spreadsheet rows - rows are numbered from 1 and the first row is the header.
+type RowRange struct {
+	First int
+	Last  int
+}
+
+// Selection chooses the mapping entries to migrate. An entry must match every selector that is set.
+type Selection struct {
+	Rows           []RowRange
+	URLs           map[string]bool
+	TaxonomyPrefix string
+	Title          *regexp.Regexp
+	PublishedFrom  *time.Time
+	PublishedTo    *time.Time
+}
+
+// ParseRows parses a comma separated list of spreadsheet rows and ranges e.g. 51-100,120.
+func ParseRows(value string) ([]RowRange, error) {
+	ranges := make([]RowRange, 0)
+	for _, item := range toSlice(value, ",") {
+		bounds := strings.SplitN(item, "-", 2)
+
+		first, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, Error{"invalid row range", err, log.Data{"rows": item}}
+		}
+
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+				return nil, Error{"invalid row range", err, log.Data{"rows": item}}
+			}
+		}
+
+		if first < FirstMappingRow || last < first {
+			return nil, Error{fmt.Sprintf("invalid row range, the first mapping row is %d", FirstMappingRow), nil, log.Data{"rows": item}}
+		}
+		ranges = append(ranges, RowRange{First: first, Last: last})
+	}
+	return ranges, nil
+}
+
+// ReadURLList reads a file of visual URLs, one per line. Blank lines and lines starting with # are ignored.
+func ReadURLList(filename string) (map[string]bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, Error{"error while attempting to open url list", err, log.Data{"filename": filename}}
+	}
+	defer f.Close()
+
+	urls := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls[NormaliseURL(line)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, Error{"error while reading url list", err, log.Data{"filename": filename}}
+	}
+	return urls, nil
+}
+
+// ParseSelectionDate parses a YYYY-MM-DD date in UK time. If endOfDay is true the last moment of the day is returned so
+// the date can be used as an inclusive upper bound.
+func ParseSelectionDate(value string, endOfDay bool) (*time.Time, error) {
+	loc, err := time.LoadLocation(wpSiteTimezone)
+	if err != nil {
+		return nil, Error{"failed to load timezone", err, log.Data{"timezone": wpSiteTimezone}}
+	}
+
+	t, err := time.ParseInLocation(selectionDateFormat, value, loc)
+	if err != nil {
+		return nil, Error{"invalid date, expected YYYY-MM-DD", err, log.Data{"date": value}}
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// Select returns the mapping entries matching the selection in the order they appear in the mapping file.
+func (s *Selection) Select(p *Plan) ([]*Article, error) {
+	selected := make([]*Article, 0)
+	found := make(map[string]bool)
+
+	for _, a := range p.Mapping.ToMigrate {
+		ok, err := s.matches(p, a)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			selected = append(selected, a)
+			found[NormaliseURL(a.VisualURL)] = true
+		}
+	}
+
+	for u := range s.URLs {
+		if !found[u] {
+			log.Info("selected visual url is not in the mapping to migrate", log.Data{"url": u})
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, Error{"no mapping rows match the selection", nil, nil}
+	}
+	if err := checkCompendiums(p.Mapping, selected); err != nil {
+		return nil, err
+	}
+	return selected, nil
+}
+
+// checkCompendiums returns an error if the selection has some but not all of the chapters of a compendium. The landing
+// page of a compendium lists the chapters migrated with it, so they must be migrated together.
+func checkCompendiums(m *Mapping, selected []*Article) error {
+	rows := make(map[int]bool, len(selected))
+	landingPages := make(map[string]bool)
+	for _, a := range selected {
+		rows[a.Row] = true
+		if uri := a.LandingPageURI(); uri != "" {
+			landingPages[uri] = true
+		}
+	}
+
+	for _, a := range m.ToMigrate {
+		if uri := a.LandingPageURI(); landingPages[uri] && !rows[a.Row] {
+			return Error{"the selection has only some of the chapters of a compendium, they must be migrated together", nil, log.Data{"compendium": uri, "row": a.Row}}
+		}
+	}
+	return nil
+}
+
+func (s *Selection) matches(p *Plan, a *Article) (bool, error) {
+	if len(s.Rows) > 0 && !s.inRows(a.Row) {
+		return false, nil
+	}
+	if s.URLs != nil && !s.URLs[NormaliseURL(a.VisualURL)] {
+		return false, nil
+	}
+	if s.TaxonomyPrefix != "" && !strings.HasPrefix(a.TaxonomyURI, s.TaxonomyPrefix) {
+		return false, nil
+	}
+	if s.Title != nil && !s.Title.MatchString(a.PostTitle) {
+		return false, nil
+	}
+
+	if s.PublishedFrom != nil || s.PublishedTo != nil {
+		post, ok := p.VisualExport.GetPost(a.VisualURL)
+		if !ok {
+			return false, nil
+		}
+
+		published, err := GetPostDate(post, PublishedDate)
+		if err != nil {
+			return false, err
+		}
+		if (s.PublishedFrom != nil && published.Before(*s.PublishedFrom)) || (s.PublishedTo != nil && published.After(*s.PublishedTo)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (s *Selection) inRows(row int) bool {
+	for _, r := range s.Rows {
+		if row >= r.First && row <= r.Last {
+			return true
+		}
+	}
+	return false
+}
+
+// ResultsPath names the results file from the rows actually selected, replacing the %d-%d of the configured
+// results-file-path with the selected row ranges e.g. visual_migration_collections_rows_51-100_120.csv
+func ResultsPath(template string, selected []*Article) string {
+	switch {
+	case strings.Contains(template, resultsRowsVerb):
+		return strings.Replace(template, resultsRowsVerb, DescribeRows(selected), 1)
+	case strings.Count(template, "%d") == 2:
+		return fmt.Sprintf(template, selected[0].Row, selected[len(selected)-1].Row)
+	default:
+		return template
+	}
+}
+
+// DescribeRows returns the selected rows as compressed ranges e.g. 51-100_120. Selections with many ranges are
+// described by their first and last row and size e.g. 12-180_23rows.
+func DescribeRows(selected []*Article) string {
+	ranges := make([]RowRange, 0)
+	for _, a := range selected {
+		if n := len(ranges); n > 0 && ranges[n-1].Last == a.Row-1 {
+			ranges[n-1].Last = a.Row
+			continue
+		}
+		ranges = append(ranges, RowRange{First: a.Row, Last: a.Row})
+	}
+
+	names := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		if r.First == r.Last {
+			names = append(names, strconv.Itoa(r.First))
+		} else {
+			names = append(names, fmt.Sprintf("%d-%d", r.First, r.Last))
+		}
+	}
+
+	if len(ranges) > maxNamedRanges {
+		return fmt.Sprintf("%d-%d_%drows", ranges[0].First, ranges[len(ranges)-1].Last, len(selected))
+	}
+	return strings.Join(names, "_")
+}
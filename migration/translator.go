@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/extensions"
+	"github.com/mmcdole/gofeed/rss"
+)
+
+const (
+	// the extension the categories of each post are stored under, keyed by category domain.
+	categoryExt = "category"
+
+	AuthorDomain   = "author"
+	TagDomain      = "post_tag"
+	CategoryDomain = "category"
+)
+
+// visualTranslator the default gofeed translator discards the domain of item categories - the wordpress export uses it
+// to distinguish authors, tags and categories so the translator keeps them as a category extension on each item.
+type visualTranslator struct {
+	gofeed.DefaultRSSTranslator
+}
+
+func (t *visualTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	result, err := t.DefaultRSSTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+
+	rssFeed, ok := feed.(*rss.Feed)
+	if !ok {
+		return result, nil
+	}
+
+	for i, rssItem := range rssFeed.Items {
+		item := result.Items[i]
+		if item.Extensions == nil {
+			item.Extensions = ext.Extensions{}
+		}
+
+		categories := make(map[string][]ext.Extension)
+		for _, c := range rssItem.Categories {
+			categories[c.Domain] = append(categories[c.Domain], ext.Extension{Name: c.Domain, Value: strings.TrimSpace(c.Value)})
+		}
+		item.Extensions[categoryExt] = categories
+	}
+	return result, nil
+}
+
+// GetCategories returns the names of the categories of the visual post in the domain e.g. AuthorDomain.
+func GetCategories(i *gofeed.Item, domain string) []string {
+	names := make([]string, 0)
+	for _, c := range i.Extensions[categoryExt][domain] {
+		names = append(names, c.Value)
+	}
+	return names
+}
@@ -0,0 +1,40 @@
+package migration
+
+import (
+	"net/url"
+	"strings"
+)
+
+// NormaliseURL returns the key used to index visual URLs so that links written differently in the mapping, the export
+// and the post content resolve to the same entry: the scheme, query, fragment and trailing slash are ignored and the
+// host and path are compared case insensitively.
+func NormaliseURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(strings.TrimSuffix(raw, "/"))
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	path := strings.ToLower(strings.TrimSuffix(u.Path, "/"))
+	return host + path
+}
+
+// ToRelativeONSURI returns links to pages on the ONS website relative to the site root e.g.
+// https://www.ons.gov.uk/economy becomes /economy. Other links are returned unchanged.
+func (p *Plan) ToRelativeONSURI(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	if !p.Sites.IsONSHost(u.Host) {
+		return raw
+	}
+
+	relative := &url.URL{Path: u.Path, RawQuery: u.RawQuery, Fragment: u.Fragment}
+	if relative.Path == "" {
+		relative.Path = "/"
+	}
+	return relative.String()
+}
@@ -0,0 +1,68 @@
+package migration
+
+import "testing"
+
+func TestNormaliseURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{
+			name:     "https",
+			raw:      "https://visual.ons.gov.uk/a-post/",
+			expected: "visual.ons.gov.uk/a-post",
+		},
+		{
+			name:     "http is the same as https",
+			raw:      "http://visual.ons.gov.uk/a-post/",
+			expected: "visual.ons.gov.uk/a-post",
+		},
+		{
+			name:     "www is ignored",
+			raw:      "https://www.visual.ons.gov.uk/a-post/",
+			expected: "visual.ons.gov.uk/a-post",
+		},
+		{
+			name:     "without a trailing slash",
+			raw:      "https://visual.ons.gov.uk/a-post",
+			expected: "visual.ons.gov.uk/a-post",
+		},
+		{
+			name:     "query is ignored",
+			raw:      "https://visual.ons.gov.uk/a-post/?utm_source=twitter",
+			expected: "visual.ons.gov.uk/a-post",
+		},
+		{
+			name:     "fragment is ignored",
+			raw:      "https://visual.ons.gov.uk/a-post/#section-2",
+			expected: "visual.ons.gov.uk/a-post",
+		},
+		{
+			name:     "host and path are case insensitive",
+			raw:      "https://Visual.ONS.gov.uk/A-Post/",
+			expected: "visual.ons.gov.uk/a-post",
+		},
+		{
+			name:     "surrounding whitespace is ignored",
+			raw:      " https://visual.ons.gov.uk/a-post/ \n",
+			expected: "visual.ons.gov.uk/a-post",
+		},
+		{
+			name:     "site root",
+			raw:      "https://visual.ons.gov.uk/",
+			expected: "visual.ons.gov.uk",
+		},
+		{
+			name:     "relative url is lower cased without the trailing slash",
+			raw:      "/A-Post/",
+			expected: "/a-post",
+		},
+	}
+
+	for _, test := range tests {
+		if actual := NormaliseURL(test.raw); actual != test.expected {
+			t.Errorf("%s: expected %q but was %q", test.name, test.expected, actual)
+		}
+	}
+}
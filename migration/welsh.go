@@ -0,0 +1,128 @@
+package migration
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/config"
+	"github.com/ONSdigital/go-ns/log"
+	"gopkg.in/yaml.v2"
+)
+
+const translationExt = ".yml"
+
+// Translation the Welsh title, content and keywords of a visual post.
+type Translation struct {
+	PostID   string   `yaml:"-"`
+	Title    string   `yaml:"title"`
+	Summary  string   `yaml:"summary"`
+	Keywords []string `yaml:"keywords"`
+	// Content the wordpress html of the post body, converted to markdown in the same way as the English post.
+	Content string `yaml:"content"`
+	// Source the export or file the translation was read from.
+	Source string `yaml:"-"`
+}
+
+// Translations the Welsh translations of the visual posts keyed by wordpress post id.
+type Translations struct {
+	byID map[string]*Translation
+}
+
+// Get returns the Welsh translation of the visual post with the wordpress post id.
+func (t *Translations) Get(postID string) (*Translation, bool) {
+	if t == nil {
+		return nil, false
+	}
+	translation, ok := t.byID[postID]
+	return translation, ok
+}
+
+// Len returns the number of translated posts.
+func (t *Translations) Len() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.byID)
+}
+
+// loadTranslations reads the Welsh translations from the welsh-rss-file and welsh-translations-dir, returning nil if
+// neither is configured. A translation in the directory replaces the translation of the same post in the export.
+func loadTranslations(cfg *config.Model) (*Translations, error) {
+	if cfg.WelshExportFile == "" && cfg.WelshTranslationsDir == "" {
+		return nil, nil
+	}
+
+	t := &Translations{byID: make(map[string]*Translation)}
+	if cfg.WelshExportFile != "" {
+		if err := t.addExport(cfg.WelshExportFile); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.WelshTranslationsDir != "" {
+		if err := t.addDir(cfg.WelshTranslationsDir); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("loaded welsh translations", log.Data{"posts": len(t.byID)})
+	return t, nil
+}
+
+// addExport adds the posts of a wordpress export of the Welsh site, matched to the English posts by post id.
+func (t *Translations) addExport(filename string) error {
+	export, err := LoadVisualExport(filename)
+	if err != nil {
+		return Error{"failed to load welsh visual export", err, log.Data{"filename": filename}}
+	}
+
+	for _, post := range export.Posts {
+		id := GetPostID(post)
+		content := ""
+		if encoded := post.Extensions["content"]["encoded"]; len(encoded) > 0 {
+			content = encoded[0].Value
+		}
+
+		t.byID[id] = &Translation{
+			PostID:   id,
+			Title:    strings.TrimSpace(post.Title),
+			Keywords: GetCategories(post, TagDomain),
+			Content:  content,
+			Source:   filename,
+		}
+	}
+	return nil
+}
+
+// addDir adds the translations in the directory, one file per post named <post id>.yml e.g.
+//
+//	title: "Faint yw fy nghyflog?"
+//	keywords: ["Enillion"]
+//	content: |
+//	  <p>...</p>
+func (t *Translations) addDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+translationExt))
+	if err != nil {
+		return Error{"failed to list welsh translations", err, log.Data{"dir": dir}}
+	}
+
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return Error{"failed to read welsh translation", err, log.Data{"path": f}}
+		}
+
+		var translation Translation
+		if err := yaml.UnmarshalStrict(b, &translation); err != nil {
+			return Error{"failed to parse welsh translation", err, log.Data{"path": f}}
+		}
+		if strings.TrimSpace(translation.Title) == "" || strings.TrimSpace(translation.Content) == "" {
+			return Error{"welsh translation must have a title and content", nil, log.Data{"path": f}}
+		}
+
+		translation.PostID = strings.TrimSuffix(filepath.Base(f), translationExt)
+		translation.Source = f
+		t.byID[translation.PostID] = &translation
+	}
+	return nil
+}
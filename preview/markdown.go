@@ -0,0 +1,144 @@
+package preview
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+)
+
+var (
+	linkDefinitionRX = regexp.MustCompile(`^\s*\[(\d+)\]: (.*)$`)
+	headingRX        = regexp.MustCompile(`^(#{1,6})\s*(.*)$`)
+	listItemRX       = regexp.MustCompile(`^- (.*)$`)
+	orderedItemRX    = regexp.MustCompile(`^\d+\. (.*)$`)
+
+	// inline patterns are matched against html escaped text
+	referenceLinkRX  = regexp.MustCompile(`\[([^\]]*)\]\[(\d+)\]`)
+	footnoteRefRX    = regexp.MustCompile(`\^(\d+)\^`)
+	interactiveRX    = regexp.MustCompile(`&lt;ons-interactive url=&#34;(.*?)&#34;(.*?)/&gt;`)
+	boxOpenRX        = regexp.MustCompile(`&lt;ons-box align=&#34;(.*?)&#34;&gt;`)
+	boxCloseRX       = regexp.MustCompile(`&lt;/ons-box&gt;`)
+	imageRX          = regexp.MustCompile(`&lt;img src=&#34;(.*?)&#34;/?&gt;`)
+	shortcodeMarkRX  = regexp.MustCompile(`\x00(.*?)\x01`)
+	shortcodeMarkers = []string{"\x00", "\x01"}
+
+	wpShortcodeRX = regexp.MustCompile(`\[/?[a-z_]+(\s[^\]]*)?\]`)
+)
+
+// RenderMarkdown renders the subset of markdown produced by the converter to HTML as the ONS website would display
+// it - ons-interactive and ons-box tags, reference style links and footnotes. Unresolved wordpress shortcodes are
+// highlighted.
+func RenderMarkdown(markdown string) template.HTML {
+	markdown = markShortcodes(markdown)
+
+	definitions := make(map[string]string)
+	lines := make([]string, 0)
+	for _, line := range strings.Split(markdown, "\n") {
+		if m := linkDefinitionRX.FindStringSubmatch(line); m != nil {
+			definitions[m[1]] = strings.TrimSpace(m[2])
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var b strings.Builder
+	list := ""
+	paragraph := make([]string, 0)
+
+	closeParagraph := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + strings.Join(paragraph, "<br>\n") + "</p>\n")
+			paragraph = paragraph[:0]
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		if list != tag {
+			closeList()
+			b.WriteString("<" + tag + ">\n")
+			list = tag
+		}
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			closeParagraph()
+			closeList()
+		case headingRX.MatchString(trimmed):
+			closeParagraph()
+			closeList()
+			m := headingRX.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(m[1])))
+			b.WriteString("<h" + level + ">" + renderInline(m[2], definitions) + "</h" + level + ">\n")
+		case listItemRX.MatchString(trimmed):
+			closeParagraph()
+			openList("ul")
+			b.WriteString("<li>" + renderInline(listItemRX.FindStringSubmatch(trimmed)[1], definitions) + "</li>\n")
+		case orderedItemRX.MatchString(trimmed):
+			closeParagraph()
+			openList("ol")
+			b.WriteString("<li>" + renderInline(orderedItemRX.FindStringSubmatch(trimmed)[1], definitions) + "</li>\n")
+		default:
+			closeList()
+			paragraph = append(paragraph, renderInline(trimmed, definitions))
+		}
+	}
+	closeParagraph()
+	closeList()
+
+	return template.HTML(b.String())
+}
+
+func renderInline(text string, definitions map[string]string) string {
+	escaped := html.EscapeString(text)
+
+	escaped = referenceLinkRX.ReplaceAllStringFunc(escaped, func(match string) string {
+		m := referenceLinkRX.FindStringSubmatch(match)
+		href, ok := definitions[m[2]]
+		if !ok {
+			return `<a class="missing">` + m[1] + `</a>`
+		}
+		return `<a href="` + html.EscapeString(href) + `" title="` + html.EscapeString(href) + `">` + m[1] + `</a>`
+	})
+	escaped = footnoteRefRX.ReplaceAllString(escaped, `<sup>$1</sup>`)
+	escaped = interactiveRX.ReplaceAllString(escaped, `<iframe class="ons-interactive" src="$1"></iframe>`)
+	escaped = boxOpenRX.ReplaceAllString(escaped, `<div class="ons-box ons-box--$1">`)
+	escaped = boxCloseRX.ReplaceAllString(escaped, `</div>`)
+	escaped = imageRX.ReplaceAllString(escaped, `<img src="$1">`)
+	escaped = shortcodeMarkRX.ReplaceAllString(escaped, `<mark class="shortcode">$1</mark>`)
+	return escaped
+}
+
+// markShortcodes wraps the unresolved shortcodes in markers that survive html escaping.
+func markShortcodes(markdown string) string {
+	indexes := zebedee.UnresolvedShortcodeIndexes(markdown)
+	for i := len(indexes) - 1; i >= 0; i-- {
+		loc := indexes[i]
+		markdown = markdown[:loc[0]] + shortcodeMarkers[0] + markdown[loc[0]:loc[1]] + shortcodeMarkers[1] + markdown[loc[1]:]
+	}
+	return markdown
+}
+
+// RenderWordPressHTML renders the content of a visual post as wordpress would, turning blank lines into paragraphs
+// and line breaks into <br>. Shortcodes are highlighted.
+func RenderWordPressHTML(content string) template.HTML {
+	var b strings.Builder
+	for _, p := range strings.Split(strings.Replace(content, "\r\n", "\n", -1), "\n\n") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		b.WriteString("<p>" + strings.Replace(p, "\n", "<br>\n", -1) + "</p>\n")
+	}
+	return template.HTML(wpShortcodeRX.ReplaceAllString(b.String(), `<mark class="shortcode">$0</mark>`))
+}
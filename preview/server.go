@@ -0,0 +1,186 @@
+package preview
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/executor"
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	postPath = "/post/"
+)
+
+// Server a local preview of the migration - each mapped post is converted on request and displayed beside the
+// wordpress original. Nothing is written to the collections directory.
+type Server struct {
+	plan *migration.Plan
+}
+
+type postSummary struct {
+	Row       int
+	Title     string
+	VisualURL string
+	URI       string
+	Status    string
+	Warnings  int
+}
+
+type postPage struct {
+	Row       int
+	Title     string
+	VisualURL string
+	URI       string
+	Error     string
+	Warnings  []string
+	Original  template.HTML
+	Converted []template.HTML
+	Markdown  []string
+	Previous  int
+	Next      int
+}
+
+func New(plan *migration.Plan) *Server {
+	return &Server{plan: plan}
+}
+
+// ListenAndServe starts the preview server on the address.
+func (s *Server) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.index)
+	mux.HandleFunc(postPath, s.post)
+
+	log.Info("preview server listening", log.Data{"address": addr})
+	return http.ListenAndServe(addr, mux)
+}
+
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	posts := make([]*postSummary, 0)
+	for _, article := range s.plan.Mapping.ToMigrate {
+		summary := &postSummary{Row: article.Row, Title: article.PostTitle, VisualURL: article.VisualURL, Status: executor.StatusSuccess}
+
+		a, _, err := executor.Convert(s.plan, article)
+		if a != nil {
+			summary.URI = a.URI
+			summary.Warnings = len(a.Warnings)
+		}
+		if err != nil {
+			summary.Status = executor.StatusError
+		}
+		posts = append(posts, summary)
+	}
+
+	s.render(w, indexTemplate, posts)
+}
+
+func (s *Server) post(w http.ResponseWriter, r *http.Request) {
+	row, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, postPath))
+	index := row - migration.FirstMappingRow
+	if err != nil || index < 0 || index >= len(s.plan.Mapping.ToMigrate) {
+		http.NotFound(w, r)
+		return
+	}
+
+	article := s.plan.Mapping.ToMigrate[index]
+	page := &postPage{
+		Row:       row,
+		Title:     article.PostTitle,
+		VisualURL: article.VisualURL,
+		Previous:  row - 1,
+		Next:      row + 1,
+	}
+	if index == 0 {
+		page.Previous = 0
+	}
+	if index == len(s.plan.Mapping.ToMigrate)-1 {
+		page.Next = 0
+	}
+
+	if visualItem, ok := s.plan.VisualExport.GetPost(article.VisualURL); ok {
+		if encoded := visualItem.Extensions["content"]["encoded"]; len(encoded) > 0 {
+			page.Original = RenderWordPressHTML(encoded[0].Value)
+		}
+	}
+
+	a, _, err := executor.Convert(s.plan, article)
+	if err != nil {
+		page.Error = err.Error()
+	}
+	if a != nil {
+		page.URI = a.URI
+		page.Warnings = a.Warnings
+		for _, section := range a.Sections {
+			page.Converted = append(page.Converted, RenderMarkdown(section.Markdown))
+			page.Markdown = append(page.Markdown, section.Markdown)
+		}
+	}
+
+	s.render(w, postTemplate, page)
+}
+
+func (s *Server) render(w http.ResponseWriter, t *template.Template, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Execute(w, data); err != nil {
+		log.Error(err, nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var (
+	styles = `<style>
+body { font-family: sans-serif; margin: 0 1em; }
+table { border-collapse: collapse; width: 100%; }
+td, th { border-bottom: 1px solid #ddd; padding: 4px; text-align: left; vertical-align: top; }
+.ERROR { color: #c00; }
+.columns { display: flex; gap: 1em; }
+.columns > div { flex: 1; min-width: 0; border: 1px solid #ddd; padding: 0 1em; overflow-wrap: break-word; }
+.warnings { background: #fff4e5; border: 1px solid #f0b400; padding: 0.5em 1em; }
+.error { background: #fde8e8; border: 1px solid #c00; padding: 0.5em 1em; }
+mark.shortcode { background: #ffd54f; }
+.ons-box { border-left: 4px solid #206095; background: #f5f5f5; padding: 0.5em 1em; }
+iframe.ons-interactive { width: 100%; height: 400px; border: 1px dashed #999; }
+a.missing { color: #c00; text-decoration: line-through; }
+pre { white-space: pre-wrap; background: #f5f5f5; padding: 1em; }
+</style>`
+
+	indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Visual migration preview</title>` + styles + `</head>
+<body>
+<h1>Visual migration preview</h1>
+<table>
+<tr><th>Row</th><th>Title</th><th>Status</th><th>Warnings</th><th>ONS URI</th></tr>
+{{range .}}<tr>
+<td>{{.Row}}</td>
+<td><a href="/post/{{.Row}}">{{.Title}}</a><br><small>{{.VisualURL}}</small></td>
+<td class="{{.Status}}">{{.Status}}</td>
+<td>{{if .Warnings}}{{.Warnings}}{{end}}</td>
+<td>{{.URI}}</td>
+</tr>{{end}}
+</table>
+</body></html>`))
+
+	postTemplate = template.Must(template.New("post").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title>` + styles + `</head>
+<body>
+<p><a href="/">All posts</a>{{if .Previous}} | <a href="/post/{{.Previous}}">Previous</a>{{end}}{{if .Next}} | <a href="/post/{{.Next}}">Next</a>{{end}}</p>
+<h1>{{.Row}}: {{.Title}}</h1>
+<p><a href="{{.VisualURL}}">{{.VisualURL}}</a> &rarr; {{.URI}}</p>
+{{if .Error}}<div class="error"><strong>Error:</strong> {{.Error}}</div>{{end}}
+{{if .Warnings}}<div class="warnings"><strong>Warnings</strong><ul>{{range .Warnings}}<li>{{.}}</li>{{end}}</ul></div>{{end}}
+<div class="columns">
+<div><h2>WordPress original</h2>{{.Original}}</div>
+<div><h2>Converted</h2>{{range .Converted}}{{.}}{{end}}</div>
+</div>
+<h2>ONS markdown</h2>
+{{range .Markdown}}<pre>{{.}}</pre>{{end}}
+</body></html>`))
+)
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+)
+
+// promote moves the content of the collections of a previous run to a review stage once it has been signed off,
+// recording the completed-by and reviewed-by users in the collection events.
+func promote(args []string) {
+	flags := flag.NewFlagSet("promote", flag.ExitOnError)
+	loadConfig := configFlags(flags, "the config with the collections directory and zebedee users")
+	resultsFile := flags.String("results", "", "promote the collections of the rows a results file migrated successfully")
+	names := flags.String("collections", "", "a comma separated list of the collections to promote")
+	stage := flags.String("stage", zebedee.StageReviewed, "the review stage to move the content to: complete or reviewed")
+	forceUnlock := flags.Bool("force-unlock", false, "remove the collections directory lock left by a run that has ended")
+	flags.Parse(args)
+
+	if *resultsFile == "" && *names == "" {
+		exit(errors.New("promote requires the -results or -collections to promote"))
+	}
+	if zebedee.ReviewStageIndex(*stage) <= 0 {
+		exit(errors.Errorf("-stage must be %s or %s but was %q", zebedee.StageComplete, zebedee.StageReviewed, *stage))
+	}
+
+	cfg, _ := loadConfig()
+	if err := cfg.ValidateCollectionsDir(); err != nil {
+		exit(errors.Wrap(err, "failed loading config"))
+	}
+
+	collections, err := selectCollections(*resultsFile, *names)
+	if err != nil {
+		exit(err)
+	}
+
+	lock := lockCollections("promote", "", *resultsFile, *forceUnlock)
+	defer lock.Release()
+
+	failed := 0
+	now := zebedee.Now()
+	for i, name := range collections {
+		if isInterrupted() {
+			failed += len(collections) - i
+			log.Info("promotion stopped, the remaining collections were not promoted", log.Data{"collections": len(collections) - i, "first": name})
+			break
+		}
+
+		if err := zebedee.PromoteCollection(name, *stage, now); err != nil {
+			failed++
+			log.ErrorC("failed to promote collection", err, log.Data{"collection": name, "stage": *stage})
+			continue
+		}
+
+		problems := zebedee.VerifyCollection(name)
+		if len(problems) > 0 {
+			failed++
+		}
+		for _, p := range problems {
+			log.Info("collection failed verification", log.Data{"collection": name, "problem": p})
+		}
+	}
+
+	log.Info("promotion complete", log.Data{"collections": len(collections), "stage": *stage, "failed": failed})
+	if failed > 0 {
+		lock.Release()
+		exit(errors.Errorf("%d of %d collections failed to promote", failed, len(collections)))
+	}
+}
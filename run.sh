@@ -1,4 +1,4 @@
 #!/usr/bin/env bash
 
 go build -o lib/visual-migration
-./lib/visual-migration
+./lib/visual-migration migrate "$@"
@@ -0,0 +1,294 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	definitionsRef = "#/definitions/"
+	dateTimeFormat = "date-time"
+	fileExt        = ".schema.json"
+)
+
+// keywords the keywords a schema may use - those implemented by Validate and the annotations it ignores. A schema using
+// any other keyword is rejected rather than silently not enforcing it.
+var keywords = map[string]bool{
+	"$ref": true, "type": true, "properties": true, "required": true, "additionalProperties": true, "items": true,
+	"enum": true, "minLength": true, "minItems": true, "pattern": true, "format": true, "definitions": true,
+	"$schema": true, "title": true, "description": true,
+}
+
+// Schema the subset of JSON Schema used to describe zebedee content: type, properties, required,
+// additionalProperties, items, enum, minLength, minItems, pattern, format date-time and references to definitions.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 interface{}        `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	MinLength            *int               `json:"minLength"`
+	MinItems             *int               `json:"minItems"`
+	Pattern              string             `json:"pattern"`
+	Format               string             `json:"format"`
+	Definitions          map[string]*Schema `json:"definitions"`
+
+	pattern *regexp.Regexp
+	root    *Schema
+}
+
+// UnmarshalJSON unmarshals the schema, returning an error if it uses a keyword that is not supported.
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+
+	unsupported := make([]string, 0)
+	for key := range fields {
+		if !keywords[key] {
+			unsupported = append(unsupported, key)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return fmt.Errorf("unsupported json schema keyword(s) %s", strings.Join(unsupported, ", "))
+	}
+
+	// the alias has no UnmarshalJSON method, so the fields are unmarshalled as normal
+	type schema Schema
+	return json.Unmarshal(b, (*schema)(s))
+}
+
+// Load reads the schema from a json file.
+func Load(filename string) (*Schema, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, migration.Error{Message: "failed to read json schema", OriginalErr: err, Params: log.Data{"path": filename}}
+	}
+
+	var s Schema
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, migration.Error{Message: "failed to unmarshal json schema", OriginalErr: err, Params: log.Data{"path": filename}}
+	}
+
+	if err := s.compile(&s); err != nil {
+		return nil, migration.Error{Message: "invalid json schema", OriginalErr: err, Params: log.Data{"path": filename}}
+	}
+	return &s, nil
+}
+
+// LoadDir reads the schemas named <name>.schema.json in the directory, keyed by name.
+func LoadDir(dir string) (map[string]*Schema, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+fileExt))
+	if err != nil {
+		return nil, migration.Error{Message: "failed to list json schemas", OriginalErr: err, Params: log.Data{"dir": dir}}
+	}
+
+	schemas := make(map[string]*Schema, len(files))
+	for _, f := range files {
+		s, err := Load(f)
+		if err != nil {
+			return nil, err
+		}
+		schemas[strings.TrimSuffix(filepath.Base(f), fileExt)] = s
+	}
+	return schemas, nil
+}
+
+func (s *Schema) compile(root *Schema) error {
+	s.root = root
+
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, definitionsRef)
+		if _, ok := root.Definitions[name]; !ok || name == s.Ref {
+			return fmt.Errorf("unresolved reference %s", s.Ref)
+		}
+	}
+
+	if s.Pattern != "" {
+		rx, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = rx
+	}
+
+	children := make([]*Schema, 0)
+	for _, p := range s.Properties {
+		children = append(children, p)
+	}
+	for _, d := range s.Definitions {
+		children = append(children, d)
+	}
+	if s.Items != nil {
+		children = append(children, s.Items)
+	}
+
+	for _, child := range children {
+		if err := child.compile(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the json document against the schema, returning a message for each problem prefixed with the path
+// of the field e.g. description.releaseDate: expected a date-time.
+func (s *Schema) Validate(doc []byte) []string {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return []string{"invalid json: " + err.Error()}
+	}
+
+	problems := make([]string, 0)
+	s.validate("", v, &problems)
+	return problems
+}
+
+func (s *Schema) validate(path string, v interface{}, problems *[]string) {
+	if s.Ref != "" {
+		s.root.Definitions[strings.TrimPrefix(s.Ref, definitionsRef)].validate(path, v, problems)
+		return
+	}
+
+	report := func(format string, args ...interface{}) {
+		field := path
+		if field == "" {
+			field = "(root)"
+		}
+		*problems = append(*problems, field+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types := s.types(); len(types) > 0 && !matchesType(types, v) {
+		report("expected %s but was %s", strings.Join(types, " or "), typeOf(v))
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		report("%v is not one of %v", v, s.Enum)
+	}
+
+	switch value := v.(type) {
+	case string:
+		if s.MinLength != nil && len([]rune(value)) < *s.MinLength {
+			report("must be at least %d characters", *s.MinLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			report("%q does not match %s", value, s.Pattern)
+		}
+		if s.Format == dateTimeFormat {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				report("%q is not a date-time", value)
+			}
+		}
+
+	case []interface{}:
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			report("must have at least %d items", *s.MinItems)
+		}
+		if s.Items != nil {
+			for i, item := range value {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, problems)
+			}
+		}
+
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, ok := value[key]; !ok {
+				*problems = append(*problems, join(path, key)+": is required")
+			}
+		}
+
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			property, ok := s.Properties[key]
+			switch {
+			case ok:
+				property.validate(join(path, key), value[key], problems)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				*problems = append(*problems, join(path, key)+": is not allowed")
+			}
+		}
+	}
+}
+
+func (s *Schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, v := range t {
+			types = append(types, fmt.Sprint(v))
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(types []string, v interface{}) bool {
+	actual := typeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func join(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,186 @@
+package schema
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// compileSchema unmarshals and compiles the schema as Load does.
+func compileSchema(t *testing.T, js string) *Schema {
+	var s Schema
+	if err := json.Unmarshal([]byte(js), &s); err != nil {
+		t.Fatalf("failed to unmarshal schema %s: %s", js, err)
+	}
+	if err := s.compile(&s); err != nil {
+		t.Fatalf("failed to compile schema %s: %s", js, err)
+	}
+	return &s
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		doc      string
+		expected []string
+	}{
+		{
+			name:     "$ref valid",
+			schema:   `{"properties": {"uri": {"$ref": "#/definitions/uri"}}, "definitions": {"uri": {"type": "string", "pattern": "^/"}}}`,
+			doc:      `{"uri": "/economy"}`,
+			expected: []string{},
+		},
+		{
+			name:     "$ref invalid",
+			schema:   `{"properties": {"uri": {"$ref": "#/definitions/uri"}}, "definitions": {"uri": {"type": "string", "pattern": "^/"}}}`,
+			doc:      `{"uri": "economy"}`,
+			expected: []string{`uri: "economy" does not match ^/`},
+		},
+		{
+			name:     "required present",
+			schema:   `{"type": "object", "required": ["type", "uri"]}`,
+			doc:      `{"type": "article", "uri": "/economy"}`,
+			expected: []string{},
+		},
+		{
+			name:     "required missing",
+			schema:   `{"type": "object", "properties": {"description": {"type": "object", "required": ["title"]}}, "required": ["type", "description"]}`,
+			doc:      `{"description": {}}`,
+			expected: []string{"type: is required", "description.title: is required"},
+		},
+		{
+			name:     "additionalProperties false",
+			schema:   `{"properties": {"uri": {"type": "string"}}, "additionalProperties": false}`,
+			doc:      `{"uri": "/economy", "b": 1, "a": 2}`,
+			expected: []string{"a: is not allowed", "b: is not allowed"},
+		},
+		{
+			name:     "additionalProperties not set",
+			schema:   `{"properties": {"uri": {"type": "string"}}}`,
+			doc:      `{"uri": "/economy", "a": 1}`,
+			expected: []string{},
+		},
+		{
+			name:     "enum valid",
+			schema:   `{"properties": {"type": {"enum": ["article", "compendium_chapter"]}}}`,
+			doc:      `{"type": "article"}`,
+			expected: []string{},
+		},
+		{
+			name:     "enum invalid",
+			schema:   `{"properties": {"type": {"enum": ["article", "compendium_chapter"]}}}`,
+			doc:      `{"type": "bulletin"}`,
+			expected: []string{"type: bulletin is not one of [article compendium_chapter]"},
+		},
+		{
+			name:     "pattern invalid",
+			schema:   `{"type": "string", "pattern": "^[a-z]+$"}`,
+			doc:      `"Economy"`,
+			expected: []string{`(root): "Economy" does not match ^[a-z]+$`},
+		},
+		{
+			name:     "date-time valid",
+			schema:   `{"type": "string", "format": "date-time"}`,
+			doc:      `"2017-03-26T01:00:00.000Z"`,
+			expected: []string{},
+		},
+		{
+			name:     "date-time invalid",
+			schema:   `{"type": "string", "format": "date-time"}`,
+			doc:      `"26/03/2017"`,
+			expected: []string{`(root): "26/03/2017" is not a date-time`},
+		},
+		{
+			name:     "minItems valid",
+			schema:   `{"type": "array", "items": {"type": "string"}, "minItems": 1}`,
+			doc:      `["economy"]`,
+			expected: []string{},
+		},
+		{
+			name:     "minItems invalid",
+			schema:   `{"type": "array", "items": {"type": "string"}, "minItems": 1}`,
+			doc:      `[]`,
+			expected: []string{"(root): must have at least 1 items"},
+		},
+		{
+			name:     "items invalid",
+			schema:   `{"properties": {"keywords": {"type": "array", "items": {"type": "string", "minLength": 1}}}}`,
+			doc:      `{"keywords": ["gdp", ""]}`,
+			expected: []string{"keywords[1]: must be at least 1 characters"},
+		},
+		{
+			name:     "integer is an integer",
+			schema:   `{"type": "integer"}`,
+			doc:      `3`,
+			expected: []string{},
+		},
+		{
+			name:     "number is not an integer",
+			schema:   `{"type": "integer"}`,
+			doc:      `3.5`,
+			expected: []string{"(root): expected integer but was number"},
+		},
+		{
+			name:     "integer is a number",
+			schema:   `{"type": "number"}`,
+			doc:      `3`,
+			expected: []string{},
+		},
+		{
+			name:     "string is not a number",
+			schema:   `{"type": ["number", "null"]}`,
+			doc:      `"3"`,
+			expected: []string{"(root): expected number or null but was string"},
+		},
+	}
+
+	for _, test := range tests {
+		actual := compileSchema(t, test.schema).Validate([]byte(test.doc))
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected %q but was %q", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestLoadRejectsUnsupportedKeywords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{name: "oneOf", schema: `{"oneOf": [{"type": "string"}, {"type": "integer"}]}`},
+		{name: "maximum in a property", schema: `{"properties": {"n": {"type": "integer", "maximum": 10}}}`},
+		{name: "maxLength in a definition", schema: `{"definitions": {"s": {"type": "string", "maxLength": 10}}}`},
+		{name: "uniqueItems in items", schema: `{"type": "array", "items": {"type": "array", "uniqueItems": true}}`},
+		{name: "unresolved $ref", schema: `{"properties": {"uri": {"$ref": "#/definitions/uri"}}}`},
+	}
+
+	for _, test := range tests {
+		filename := filepath.Join(dir, "test"+fileExt)
+		if err := ioutil.WriteFile(filename, []byte(test.schema), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(filename); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestLoadDirShippedSchemas(t *testing.T) {
+	schemas, err := LoadDir("../resources/schemas")
+	if err != nil {
+		t.Fatalf("failed to load the shipped schemas: %s", err)
+	}
+	if len(schemas) == 0 {
+		t.Fatal("no schemas loaded")
+	}
+}
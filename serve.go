@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/preview"
+)
+
+// serve runs a local preview server showing each mapped post converted beside the wordpress original. Nothing is
+// written to the collections directory.
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	loadConfig := configFlags(flags, "the config to use when converting the posts")
+	addr := flags.String("addr", "localhost:8080", "the address to serve the preview on")
+	flags.Parse(args)
+
+	_, plan := loadConfig()
+
+	if err := preview.New(plan).ListenAndServe(*addr); err != nil {
+		exit(err)
+	}
+}
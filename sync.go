@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/executor"
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/pkg/errors"
+)
+
+// syncExport re-migrates the visual posts added or changed in the configured export since an older export was migrated.
+func syncExport(args []string) {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	loadConfig := configFlags(flags, "the config with the newer wordpress export")
+	oldExport := flags.String("old", "", "the wordpress export the previous run migrated")
+	manifest := flags.String("manifest", "", "the results file of the previous run")
+	forceUnlock := flags.Bool("force-unlock", false, "remove the collections directory lock left by a run that has ended")
+	flags.Parse(args)
+
+	if *oldExport == "" || *manifest == "" {
+		flags.Usage()
+		exit(errors.New("sync requires the -old export and the -manifest of the previous run"))
+	}
+
+	cfg, plan := loadConfig()
+	if err := cfg.ValidateCollectionsDir(); err != nil {
+		exit(errors.Wrap(err, "failed loading config"))
+	}
+
+	older, err := migration.LoadVisualExport(*oldExport)
+	if err != nil {
+		exit(err)
+	}
+
+	results, err := executor.ReadResults(*manifest)
+	if err != nil {
+		exit(err)
+	}
+
+	grouping, err := executor.NewGrouping(cfg)
+	if err != nil {
+		exit(err)
+	}
+
+	schedule, err := executor.NewSchedule(cfg, time.Now())
+	if err != nil {
+		exit(err)
+	}
+
+	name := strings.TrimSuffix(*manifest, ".csv")
+	lock := lockCollections("sync", "", name+"_sync_results.csv", *forceUnlock)
+	defer lock.Release()
+
+	e, err := executor.New(cfg, plan, grouping, schedule, name+"_sync_results.csv")
+	if err != nil {
+		lock.Release()
+		exit(err)
+	}
+	stopOnInterrupt(e)
+
+	entries := e.Sync(results, migration.DiffExports(older, plan.VisualExport))
+	err = executor.WriteSyncReport(name+"_sync.csv", entries)
+
+	// the results are written whether or not the sync report could be, exit skips the deferred calls
+	e.Close()
+	if err != nil {
+		lock.Release()
+		exit(err)
+	}
+}
@@ -2,7 +2,6 @@ package util
 
 import (
 	"regexp"
-	"os"
 	"errors"
 	"strings"
 )
@@ -17,7 +16,6 @@ func init() {
 	validFileNameRegex, err = regexp.Compile(validFilePattern)
 	if err != nil {
 		panic(errors.New("failed to compile valid filename regex"))
-		os.Exit(1)
 	}
 }
 
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/executor"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+)
+
+// validate checks the selected mapping rows would migrate without writing anything, exiting with an error if any
+// would fail.
+func validate(args []string) {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	loadConfig := configFlags(flags, "the config to validate the migration with")
+	selection := selectionFlags(flags)
+	flags.Parse(args)
+
+	cfg, plan := loadConfig()
+
+	batch, err := selection(plan)
+	if err != nil {
+		exit(err)
+	}
+
+	grouping, err := executor.NewGrouping(cfg)
+	if err != nil {
+		exit(err)
+	}
+
+	schedule, err := executor.NewSchedule(cfg, time.Now())
+	if err != nil {
+		exit(err)
+	}
+
+	failed, warnings := 0, 0
+	for _, r := range executor.Validate(plan, grouping, schedule, batch) {
+		warnings += len(r.Warnings)
+		if r.Status == executor.StatusError {
+			failed++
+			log.Info("row would fail to migrate", log.Data{"row": r.Row, "url": r.VisualURL, "error": r.Error, "params": r.ErrorParams})
+		}
+	}
+
+	log.Info("validation complete", log.Data{"rows": len(batch), "errors": failed, "warnings": warnings})
+	if failed > 0 {
+		exit(errors.Errorf("%d of %d rows would fail to migrate", failed, len(batch)))
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/executor"
+	"github.com/ONSdigital/dp-visual-ons-migration/zebedee"
+	"github.com/ONSdigital/go-ns/log"
+	"github.com/pkg/errors"
+)
+
+// verify checks existing collections are readable by zebedee, exiting with an error if any are not. Every collection
+// in the collections directory is checked unless the collections or a results file are given.
+func verify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	loadConfig := configFlags(flags, "the config with the collections directory to verify")
+	resultsFile := flags.String("results", "", "verify the collections of the rows a results file migrated successfully")
+	names := flags.String("collections", "", "a comma separated list of the collections to verify")
+	flags.Parse(args)
+
+	loadConfig()
+
+	collections, err := selectCollections(*resultsFile, *names)
+	if err != nil {
+		exit(err)
+	}
+
+	failed := 0
+	for _, name := range collections {
+		problems := zebedee.VerifyCollection(name)
+		if len(problems) == 0 {
+			continue
+		}
+
+		failed++
+		for _, p := range problems {
+			log.Info("collection failed verification", log.Data{"collection": name, "problem": p})
+		}
+	}
+
+	log.Info("verification complete", log.Data{"collections": len(collections), "failed": failed})
+	if failed > 0 {
+		exit(errors.Errorf("%d of %d collections failed verification", failed, len(collections)))
+	}
+}
+
+func selectCollections(resultsFile string, names string) ([]string, error) {
+	switch {
+	case names != "":
+		collections := make([]string, 0)
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				collections = append(collections, name)
+			}
+		}
+		return collections, nil
+
+	case resultsFile != "":
+		results, err := executor.ReadResults(resultsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		collections := make([]string, 0)
+		seen := make(map[string]bool)
+		for _, r := range results {
+			if r.Status == executor.StatusSuccess && !seen[r.CollectionName] {
+				seen[r.CollectionName] = true
+				collections = append(collections, r.CollectionName)
+			}
+		}
+		return collections, nil
+
+	default:
+		return zebedee.ListCollections()
+	}
+}
@@ -9,22 +9,63 @@ import (
 	"unicode"
 	"strconv"
 	"sort"
+	"time"
+	"github.com/ONSdigital/go-ns/log"
 )
 
 const (
-	pageType             = "article"
-	collectionDateFormat = "2006-01-02T03:04:05.000Z"
-	visualDateFormat     = "Mon, 02 Jan 2006 03:02:05 Z0700"
-	hrefTag              = "href"
-	articleDateFormat    = "2006-01-02"
+	releaseDateFormat = "2006-01-02T15:04:05.000Z07:00"
+	hrefTag           = "href"
+	articleDateFormat = "2006-01-02"
+
+	UnmappedAuthorWarning = "no contact details for author, using team mailbox:"
+
+	// the steps of converting a section from wordpress html to ONS markdown
+	StageWordPress    = "wordpress"
+	StageMarkdown     = "markdown"
+	StageInteractives = "interactives"
+	StageFootnotes    = "footnotes"
+	StageExplanations = "explanations"
 )
 
-func CreateArticle(details *migration.Article, visualItem *gofeed.Item) *Article {
+var (
+	// Stages the conversion steps in the order they are applied.
+	Stages = []string{StageWordPress, StageMarkdown, StageInteractives, StageFootnotes, StageExplanations}
+)
+
+// StageFunc is called with the markdown of a section after a conversion step.
+type StageFunc func(stage string, section int, markdown string)
+
+var (
+	// ReleaseDateSource which visual post date is used as the article release date - migration.PublishedDate or
+	// migration.ModifiedDate.
+	ReleaseDateSource = migration.PublishedDate
+)
+
+func CreateArticle(plan *migration.Plan, details *migration.Article, visualItem *gofeed.Item) (*Article, error) {
+	releaseDate, err := migration.GetPostDate(visualItem, ReleaseDateSource)
+	if err != nil {
+		return nil, err
+	}
+
+	contact, warnings, err := getContact(plan, visualItem)
+	if err != nil {
+		return nil, err
+	}
+
+	keywords, keywordWarnings := getKeywords(plan.Vocabulary, details, visualItem)
+	warnings = append(warnings, keywordWarnings...)
+
+	pageType := details.PageType
+	if pageType == "" {
+		pageType = migration.PageArticle
+	}
 
 	desc := Description{
 		Title:       details.PostTitle,
-		Keywords:    details.Keywords,
-		ReleaseDate: visualItem.PublishedParsed.Format(collectionDateFormat),
+		Keywords:    keywords,
+		ReleaseDate: FormatReleaseDate(releaseDate),
+		Contact:     contact,
 	}
 
 	encoded := visualItem.Extensions["content"]["encoded"]
@@ -33,56 +74,117 @@ func CreateArticle(details *migration.Article, visualItem *gofeed.Item) *Article
 		Markdown: encoded[0].Value,
 	}
 
-	links := make([]*RelatedLink, 0)
-
-	//var thumbnailID string
-	links, _ = ParseMetadata(visualItem)
-	sort.Slice(links, func(i, j int) bool {
-		return links[i].ID < links[j].ID
-	})
+	links, _, linkWarnings := ParseMetadata(visualItem)
+	warnings = append(warnings, linkWarnings...)
 
 	return &Article{
-		PDFTable:                  []interface{}{},
+		PDFTable:                  []*Figure{},
 		Description:               desc,
-		IsPrototypeArticle:        true,
+		IsPrototypeArticle:        pageType == migration.PageArticle,
+		IsReleaseDateEnabled:      true,
 		Sections:                  []*MarkdownSection{section},
-		Accordion:                 []interface{}{},
-		RelatedData:               []interface{}{},
-		RelatedDocs:               []interface{}{},
-		Charts:                    []interface{}{},
-		Tables:                    []interface{}{},
-		Equations:                 []interface{}{},
+		Accordion:                 []*MarkdownSection{},
+		RelatedData:               []*RelatedLink{},
+		RelatedDocs:               []*RelatedLink{},
+		Charts:                    []*Figure{},
+		Tables:                    []*Figure{},
+		Images:                    []*Figure{},
+		Equations:                 []*Figure{},
 		Links:                     links,
-		RelatedMethodology:        []interface{}{},
-		RelatedMethodologyArticle: []interface{}{},
-		Versions:                  []interface{}{},
+		RelatedMethodology:        []*RelatedLink{},
+		RelatedMethodologyArticle: []*RelatedLink{},
+		Versions:                  []*Version{},
+		Alerts:                    []*Alert{},
 		URI:                       details.TaxonomyURI,
 		Type:                      pageType,
-		Topics:                    []interface{}{},
+		CompendiumTitle:           details.Compendium,
+		Topics:                    getTopics(plan.Vocabulary, visualItem),
 		ImageURI:                  "",
+		Warnings:                  warnings,
+	}, nil
+}
+
+// getContact returns the contact details for the author of the visual post. If the author has no contact details the
+// team mailbox is used and a warning returned, if there is no team mailbox either the article cannot be migrated.
+func getContact(plan *migration.Plan, visualItem *gofeed.Item) (Contact, []string, error) {
+	warnings := make([]string, 0)
+	authors := migration.GetCategories(visualItem, migration.AuthorDomain)
+	if len(authors) == 0 {
+		authors = []string{""}
+		warnings = append(warnings, "visual post has no author")
+	}
+
+	author := authors[0]
+	c, ok := plan.Contacts.GetContact(author)
+	if !ok && author != "" {
+		warnings = append(warnings, fmt.Sprintf("%s %q", UnmappedAuthorWarning, author))
+	}
+
+	if c == nil || c.Name == "" || c.Email == "" {
+		return Contact{}, nil, migration.Error{
+			Message: "no contact details for visual post author",
+			Params:  log.Data{"author": author, "title": visualItem.Title},
+		}
 	}
+	return Contact{Name: c.Name, Email: c.Email, Phone: c.Telephone}, warnings, nil
 }
 
+// FormatReleaseDate formats the time as a UTC RFC 3339 timestamp with millisecond precision e.g. 2015-06-03T06:30:03.000Z
+func FormatReleaseDate(t time.Time) string {
+	return t.UTC().Format(releaseDateFormat)
+}
+
+// Article a converted visual post. It is the data.json of an ONS article page, see resources/schemas, and is converted
+// to the structure of the other page types by Page.
 type Article struct {
-	PDFTable                  []interface{}      `json:"pdfTable"`
+	PDFTable                  []*Figure          `json:"pdfTable"`
 	IsPrototypeArticle        bool               `json:"isPrototypeArticle"`
+	IsReleaseDateEnabled      bool               `json:"isReleaseDateEnabled"`
 	Sections                  []*MarkdownSection `json:"sections"`
-	Accordion                 []interface{}      `json:"accordion"`
-	RelatedData               []interface{}      `json:"relatedData"`
-	RelatedDocs               []interface{}      `json:"relatedDocuments"`
-	Charts                    []interface{}      `json:"charts"`
-	Tables                    []interface{}      `json:"tables"`
-	images                    []interface{}      `json:"images"`
-	Equations                 []interface{}      `json:"equations"`
+	Accordion                 []*MarkdownSection `json:"accordion"`
+	RelatedData               []*RelatedLink     `json:"relatedData"`
+	RelatedDocs               []*RelatedLink     `json:"relatedDocuments"`
+	Charts                    []*Figure          `json:"charts"`
+	Tables                    []*Figure          `json:"tables"`
+	Images                    []*Figure          `json:"images"`
+	Equations                 []*Figure          `json:"equations"`
 	Links                     []*RelatedLink     `json:"links"`
-	RelatedMethodology        []interface{}      `json:"relatedMethodology"`
-	RelatedMethodologyArticle []interface{}      `json:"relatedMethodologyArticle"`
-	Versions                  []interface{}      `json:"versions"`
+	RelatedMethodology        []*RelatedLink     `json:"relatedMethodology"`
+	RelatedMethodologyArticle []*RelatedLink     `json:"relatedMethodologyArticle"`
+	Versions                  []*Version         `json:"versions"`
+	Alerts                    []*Alert           `json:"alerts"`
+	LatestReleaseURI          string             `json:"latestReleaseUri,omitempty"`
 	Type                      string             `json:"type"`
 	URI                       string             `json:"uri"`
 	Description               Description        `json:"description"`
-	Topics                    []interface{}      `json:"topics"`
+	Topics                    []*RelatedLink     `json:"topics"`
 	ImageURI                  string             `json:"imageUri"`
+	Warnings                  []string           `json:"-"`
+	// CompendiumTitle the title of the compendium landing page of a compendium chapter.
+	CompendiumTitle string `json:"-"`
+}
+
+// Figure a chart, table, image, equation or pdf table of the page, stored alongside its data.json.
+type Figure struct {
+	Title    string `json:"title"`
+	Filename string `json:"filename"`
+	URI      string `json:"uri,omitempty"`
+	Version  string `json:"version,omitempty"`
+}
+
+// Version a previous version of the page, kept when it is corrected or updated.
+type Version struct {
+	URI              string `json:"uri"`
+	UpdateDate       string `json:"updateDate"`
+	CorrectionNotice string `json:"correctionNotice"`
+	Label            string `json:"label,omitempty"`
+}
+
+// Alert a correction or notice shown at the top of the page.
+type Alert struct {
+	Date     string `json:"date"`
+	Markdown string `json:"markdown"`
+	Type     string `json:"type"`
 }
 
 type MarkdownSection struct {
@@ -101,6 +203,7 @@ type Description struct {
 	NextRelease       string   `json:"nextRelease"`
 	Edition           string   `json:"edition"`
 	Abstraction       string   `json:"_abstract"`
+	Summary           string   `json:"summary,omitempty"`
 	Unit              string   `json:"unit"`
 	PreUnit           string   `json:"preUnit"`
 	Source            string   `json:"source"`
@@ -113,64 +216,110 @@ type Contact struct {
 }
 
 type RelatedLink struct {
-	Title string `json:"title"`
-	URI   string `json:"uri"`
-	ID    int    `json:"-"`
+	Title   string `json:"title"`
+	URI     string `json:"uri"`
+	Summary string `json:"summary,omitempty"`
+	ID      int    `json:"-"`
 }
 
-func ParseMetadata(visualItem *gofeed.Item) ([]*RelatedLink, string) {
+// ParseMetadata reads the "more information" related links and the thumbnail ID from the visual post metadata. Links
+// without a URL or title are dropped and reported in the returned warnings.
+func ParseMetadata(visualItem *gofeed.Item) ([]*RelatedLink, string, []string) {
 	metadata := visualItem.Extensions["wp"]["postmeta"]
 	rawLinks := map[int]*RelatedLink{}
 	thumbnailID := ""
+	warnings := make([]string, 0)
 
 	for _, mi := range metadata {
 
 		metaKey := mi.Children["meta_key"][0]
-		if moreInfoURLRX.MatchString(metaKey.Value) {
-			index := metaKey.Value
-			index = strings.Replace(index, "more_information_", "", 1)
-			index = strings.Replace(index, "_url", "", 1)
-			i, _ := strconv.Atoi(index)
-
-			if l, ok := rawLinks[i]; ok {
-				l.URI = mi.Children["meta_value"][0].Value
-			} else {
-				rawLinks[i] = &RelatedLink{ID: i, URI: mi.Children["meta_value"][0].Value}
+		if match := moreInfoRX.FindStringSubmatch(metaKey.Value); match != nil {
+			i, err := strconv.Atoi(match[1])
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("ignoring related link metadata with invalid index %q", metaKey.Value))
+				continue
 			}
-		} else if moreInfoTitleRX.MatchString(metaKey.Value) {
-			index := metaKey.Value
-			index = strings.Replace(index, "more_information_", "", 1)
-			index = strings.Replace(index, "_link_title", "", 1)
-			i, _ := strconv.Atoi(index)
-
-			if l, ok := rawLinks[i]; ok {
-				l.Title = mi.Children["meta_value"][0].Value
-			} else {
-				rawLinks[i] = &RelatedLink{ID: i, Title: mi.Children["meta_value"][0].Value}
+
+			l, ok := rawLinks[i]
+			if !ok {
+				l = &RelatedLink{ID: i}
+				rawLinks[i] = l
 			}
-		} else if metaKey.Value == "_thumbnail_id" {
+
+			value := strings.TrimSpace(mi.Children["meta_value"][0].Value)
+			switch match[2] {
+			case moreInfoURL:
+				l.URI = value
+			case moreInfoTitle:
+				l.Title = value
+			case moreInfoDescription:
+				l.Summary = value
+			}
+		} else if metaKey.Value == thumbnailIDKey {
 			thumbnailID = mi.Children["meta_value"][0].Value
 		}
 	}
 
+	// links are visited in index order so the warnings are in the same order on every run
+	indexes := make([]int, 0, len(rawLinks))
+	for i := range rawLinks {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
 	links := make([]*RelatedLink, 0)
-	for _, l := range rawLinks {
+	for _, i := range indexes {
+		l := rawLinks[i]
+		if l.URI == "" || l.Title == "" {
+			warnings = append(warnings, fmt.Sprintf("dropped related link %d without a url or title: %q %q", l.ID, l.Title, l.URI))
+			continue
+		}
 		links = append(links, l)
 	}
-	return links, thumbnailID
+	return links, thumbnailID, warnings
 }
 
 func (a *Article) ConvertToONSFormat(plan *migration.Plan) error {
-	for _, s := range a.Sections {
+	return a.ConvertToONSFormatStages(plan, nil)
+}
+
+// ConvertToONSFormatStages converts the article, calling stage with the markdown of each section after every
+// transformation step.
+func (a *Article) ConvertToONSFormatStages(plan *migration.Plan, stage StageFunc) error {
+	if stage == nil {
+		stage = func(string, int, string) {}
+	}
+
+	for i, s := range a.Sections {
+		stage(StageWordPress, i, s.Markdown)
+
 		markdown, err := ConvertHTMLToONSMarkdown(s.Markdown, plan)
 		if err != nil {
 			return err
 		}
 
 		s.Markdown = markdown
+		stage(StageMarkdown, i, s.Markdown)
 		s.fixInteractiveLinks()
+		stage(StageInteractives, i, s.Markdown)
 		s.fixFootnotes()
+		stage(StageFootnotes, i, s.Markdown)
 		s.fixExplanations()
+		stage(StageExplanations, i, s.Markdown)
+
+		for _, shortcode := range UnresolvedShortcodes(s.Markdown) {
+			a.Warnings = append(a.Warnings, fmt.Sprintf("unresolved wordpress shortcode %s", shortcode))
+		}
+	}
+
+	// related links are rewritten in the same way as the links in the article body, links to other ONS pages are made
+	// relative to the site.
+	for _, l := range a.Links {
+		uri, err := plan.GetMigratedURL(l.URI)
+		if err != nil {
+			return err
+		}
+		l.URI = plan.ToRelativeONSURI(uri)
 	}
 	return nil
 }
@@ -219,6 +368,28 @@ func (s *MarkdownSection) fixExplanations() {
 	}
 }
 
+// UnresolvedShortcodes returns the wordpress shortcodes e.g. [caption ...] left in the markdown after conversion.
+func UnresolvedShortcodes(markdown string) []string {
+	shortcodes := make([]string, 0)
+	for _, loc := range UnresolvedShortcodeIndexes(markdown) {
+		shortcodes = append(shortcodes, markdown[loc[0]:loc[1]])
+	}
+	return shortcodes
+}
+
+// UnresolvedShortcodeIndexes returns the start and end index of each wordpress shortcode left in the markdown,
+// ignoring the text of markdown links e.g. [here][3].
+func UnresolvedShortcodeIndexes(markdown string) [][]int {
+	indexes := make([][]int, 0)
+	for _, loc := range shortcodeRX.FindAllStringIndex(markdown, -1) {
+		if markdownLinkRefRX.MatchString(markdown[loc[1]:]) {
+			continue
+		}
+		indexes = append(indexes, loc)
+	}
+	return indexes
+}
+
 func trimTrailingWhiteSpace(body string) string {
 	return strings.TrimRightFunc(body, func(c rune) bool {
 		return unicode.IsSpace(c)
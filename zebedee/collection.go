@@ -9,8 +9,9 @@ import (
 	"github.com/ONSdigital/go-ns/log"
 	"regexp"
 	"errors"
+	"time"
 	"github.com/ONSdigital/dp-visual-ons-migration/migration"
-	"github.com/ONSdigital/dp-visual-ons-migration/util"
+	"path/filepath"
 )
 
 const (
@@ -20,12 +21,26 @@ const (
 	dataJSON        = "data.json"
 	approvalStatus  = "NOT_STARTED"
 	collectionOwner = "PUBLISHING_SUPPORT"
-	collectionType  = "manual"
+
+	ManualCollection    = "manual"
+	ScheduledCollection = "scheduled"
+
+	// the format zebedee uses to serialise collection dates
+	publishDateFormat = "2006-01-02T15:04:05.000Z"
 )
 
 var (
 	collectionDirs     = []string{inProgress, complete, reviewed}
 	CollectionsRoot    = ""
+
+	// CollectionIDNamespace when set collection IDs are generated deterministically from this namespace, otherwise
+	// they are random.
+	CollectionIDNamespace *uuid.UUID
+
+	// RunTime when set the time recorded in collection histories and version dates instead of now, so a run can be
+	// reproduced.
+	RunTime time.Time
+
 	validFilePattern   = "[^a-zA-Z0-9]+"
 	validFileNameRegex *regexp.Regexp
 )
@@ -35,7 +50,6 @@ func init() {
 	validFileNameRegex, err = regexp.Compile(validFilePattern)
 	if err != nil {
 		panic(errors.New("failed to compile valid filename regex"))
-		os.Exit(1)
 	}
 }
 
@@ -58,47 +72,48 @@ type Collection struct {
 	ID                    string              `json:"id"`
 	Name                  string              `json:"name"`
 	Type                  string              `json:"type"`
+	PublishDate           string              `json:"publishDate,omitempty"`
+	// Events the history of the collection and EventsByURI the history of each file in it, recorded when migrated
+	// content is moved through the review stages.
+	Events      []*Event            `json:"events,omitempty"`
+	EventsByURI map[string][]*Event `json:"eventsByUri,omitempty"`
 }
 
-func ToCollectionName(index int, name string) string {
-	return fmt.Sprintf("viz_%d_%s", index, util.SanitisedFilename(name))
-}
-
-func CreateCollection(name string) (*Collection, error) {
-	collectionRootDir := fmt.Sprintf("%s/%s", CollectionsRoot, name)
+// CreateCollection creates a new collection on disk. If publishDate is not nil the collection is scheduled to publish at
+// that time, otherwise it is a manual collection. idSeed identifies the content of the collection and is used to
+// generate the collection ID when deterministic IDs are enabled.
+func CreateCollection(name string, idSeed string, publishDate *time.Time) (*Collection, error) {
+	c := NewCollection(name, idSeed, publishDate)
+	collectionRootDir := c.Metadata.Root
 
-	if _, err := os.Stat(collectionRootDir); err == nil {
-		msg := fmt.Sprintf("the collection %s already exist, skipping migration", name)
-		return nil, migration.Error{Message: msg, Params: log.Data{"path": collectionRootDir}, OriginalErr: nil}
+	if CollectionExists(name) {
+		return nil, migration.Error{Message: "the collection already exists, skipping migration", Params: log.Data{"collection": name, "path": collectionRootDir}, OriginalErr: nil}
 	}
 
-	metadata := &CollectionMetadata{
-		Root:           collectionRootDir,
-		CollectionJSON: collectionRootDir + ".json",
-		InProgress:     collectionRootDir + "/" + inProgress,
-		Complete:       collectionRootDir + "/" + complete,
-		Reviewed:       collectionRootDir + "/" + reviewed,
-		DataJSON:       collectionRootDir + "/" + dataJSON,
+	if CompletedBy != "" {
+		c.addEvents(EventCreated, CompletedBy, Now(), nil)
 	}
 
-	c := &Collection{
-		Metadata:              metadata,
-		ApprovalStatus:        approvalStatus,
-		CollectionOwner:       collectionOwner,
-		IsEncrypted:           false,
-		PublishComplete:       false,
-		Type:                  collectionType,
-		ID:                    newCollectionID(name),
-		Name:                  name,
-		TimeSeriesImportFiles: []string{},
+	b, err := MarshalCollection(c)
+	if err != nil {
+		return nil, err
 	}
 
-	b, err := json.MarshalIndent(c, "", "  ")
-	if err != nil {
-		return nil, migration.Error{Message: "failed to marshall zebedee json", OriginalErr: err, Params: nil}
+	// the root directory is created exclusively, if two runs race to create the same collection only one succeeds and
+	// the other leaves the collection untouched.
+	log.Info("creating collection directory", log.Data{"path": collectionRootDir})
+	if err := os.Mkdir(collectionRootDir, 0755); err != nil {
+		if os.IsExist(err) {
+			return nil, migration.Error{Message: "the collection already exists, skipping migration", Params: log.Data{"collection": name, "path": collectionRootDir}, OriginalErr: nil}
+		}
+		return nil, migration.Error{
+			Message:     "failed to created collection dir",
+			OriginalErr: err,
+			Params:      log.Data{"path": collectionRootDir},
+		}
 	}
 
-	for _, path := range []string{c.Metadata.Root, c.Metadata.InProgress, c.Metadata.Complete, c.Metadata.Reviewed} {
+	for _, path := range []string{c.Metadata.InProgress, c.Metadata.Complete, c.Metadata.Reviewed} {
 		log.Info("creating collection directory", log.Data{"path": path})
 
 		if err := os.Mkdir(path, 0755); err != nil {
@@ -111,7 +126,8 @@ func CreateCollection(name string) (*Collection, error) {
 		}
 	}
 
-	if err := writeToFile(c.Metadata.CollectionJSON, b); err != nil {
+	if err := writeNewFile(c.Metadata.CollectionJSON, b); err != nil {
+		os.RemoveAll(collectionRootDir)
 		return nil, migration.Error{
 			Message:     "failed to write collection json file",
 			OriginalErr: err,
@@ -121,11 +137,32 @@ func CreateCollection(name string) (*Collection, error) {
 	return c, nil
 }
 
+// NewCollection returns the collection without creating it on disk.
+func NewCollection(name string, idSeed string, publishDate *time.Time) *Collection {
+	c := &Collection{
+		Metadata:              newCollectionMetadata(name),
+		ApprovalStatus:        approvalStatus,
+		CollectionOwner:       collectionOwner,
+		IsEncrypted:           false,
+		PublishComplete:       false,
+		Type:                  ManualCollection,
+		ID:                    newCollectionID(name, idSeed),
+		Name:                  name,
+		TimeSeriesImportFiles: []string{},
+	}
+
+	if publishDate != nil {
+		c.Type = ScheduledCollection
+		c.PublishDate = FormatCollectionDate(*publishDate)
+	}
+	return c
+}
+
 func (c Collection) ResolveInProgress(path string) string {
 	return c.Metadata.InProgress + path
 }
 
-func (c Collection) AddArticle(zebedeeArticle *Article, visualArticle *migration.Article) error {
+func (c *Collection) AddArticle(zebedeeArticle *Article, visualArticle *migration.Article) error {
 	path := fmt.Sprintf("%s%s", c.Metadata.InProgress, zebedeeArticle.URI)
 
 	if err := os.MkdirAll(path, 0755); err != nil {
@@ -136,12 +173,27 @@ func (c Collection) AddArticle(zebedeeArticle *Article, visualArticle *migration
 		}
 	}
 
-	b, err := json.MarshalIndent(zebedeeArticle, "", "	")
+	// a published page is kept as the previous version of the article rather than overwritten when it is published
+	if IsPublished(zebedeeArticle.URI) {
+		if err := c.addVersion(zebedeeArticle); err != nil {
+			return err
+		}
+	}
+
+	b, err := MarshalArticle(zebedeeArticle)
 	if err != nil {
 		return err
 	}
 	path = path + "/" + dataJSON
 
+	if problems := ValidatePage(zebedeeArticle.Type, b); len(problems) > 0 {
+		return migration.Error{
+			Message:     "article does not match the schema of its page type",
+			OriginalErr: nil,
+			Params:      log.Data{"collection": c.Name, "path": path, "problems": problems},
+		}
+	}
+
 	if err := writeToFile(path, b); err != nil {
 		return migration.Error{
 			Message:     "failed to write article json",
@@ -149,13 +201,171 @@ func (c Collection) AddArticle(zebedeeArticle *Article, visualArticle *migration
 			Params:      log.Data{"collection": c.Name, "path": path},
 		}
 	}
+
+	uris := []string{zebedeeArticle.URI + "/" + dataJSON}
+	if zebedeeArticle.Type == migration.PageCompendiumChapter {
+		if err := c.addToCompendium(zebedeeArticle); err != nil {
+			return err
+		}
+		uris = append(uris, CompendiumURI(zebedeeArticle.URI)+"/"+dataJSON)
+	}
+	return c.recordCreated(uris...)
+}
+
+// CollectionExists returns true if the collection is in the collections directory.
+func CollectionExists(name string) bool {
+	_, err := os.Stat(newCollectionMetadata(name).Root)
+	return err == nil
+}
+
+// OpenCollection reads an existing collection, returning an error if it is not in the collections directory.
+func OpenCollection(name string) (*Collection, error) {
+	metadata := newCollectionMetadata(name)
+
+	b, err := ioutil.ReadFile(metadata.CollectionJSON)
+	if err != nil {
+		return nil, migration.Error{Message: "failed to read collection json", OriginalErr: err, Params: log.Data{"path": metadata.CollectionJSON}}
+	}
+
+	var c Collection
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, migration.Error{Message: "failed to unmarshal collection json", OriginalErr: err, Params: log.Data{"path": metadata.CollectionJSON}}
+	}
+
+	if _, err := os.Stat(metadata.InProgress); err != nil {
+		return nil, migration.Error{Message: "collection has no inprogress directory", OriginalErr: err, Params: log.Data{"path": metadata.InProgress}}
+	}
+
+	c.Metadata = metadata
+	return &c, nil
+}
+
+func newCollectionMetadata(name string) *CollectionMetadata {
+	collectionRootDir := fmt.Sprintf("%s/%s", CollectionsRoot, name)
+
+	return &CollectionMetadata{
+		Root:           collectionRootDir,
+		CollectionJSON: collectionRootDir + ".json",
+		InProgress:     collectionRootDir + "/" + inProgress,
+		Complete:       collectionRootDir + "/" + complete,
+		Reviewed:       collectionRootDir + "/" + reviewed,
+		DataJSON:       collectionRootDir + "/" + dataJSON,
+	}
+}
+
+// MarshalCollection returns the collection json as it is written to disk.
+func MarshalCollection(c *Collection) ([]byte, error) {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, migration.Error{Message: "failed to marshall zebedee json", OriginalErr: err, Params: nil}
+	}
+	return b, nil
+}
+
+// MarshalArticle returns the data.json of the article's page type as it is written to disk.
+func MarshalArticle(a *Article) ([]byte, error) {
+	return json.MarshalIndent(a.Page(), "", "	")
+}
+
+// FormatCollectionDate formats the time in UTC as zebedee expects collection dates.
+func FormatCollectionDate(t time.Time) string {
+	return t.UTC().Format(publishDateFormat)
+}
+
+// Now returns the RunTime if it is set, otherwise the current time.
+func Now() time.Time {
+	if RunTime.IsZero() {
+		return time.Now()
+	}
+	return RunTime
+}
+
+// SetCollectionIDNamespace enables deterministic collection IDs - re-running a migration with the same namespace and
+// inputs generates the same IDs.
+func SetCollectionIDNamespace(namespace string) {
+	ns := uuid.NewV5(uuid.NamespaceURL, namespace)
+	CollectionIDNamespace = &ns
+}
+
+// ArticlePath returns the path of the data.json of the article with the uri in the collection, in the directory of the
+// review stage that holds it - inprogress if the collection does not have the article.
+func ArticlePath(collectionName string, uri string) string {
+	return pagePath(collectionName, uri, dataJSON)
+}
+
+// pagePath returns the path of the page json file with the name at the review stage that holds it, or in progress.
+func pagePath(collectionName string, uri string, name string) string {
+	m := newCollectionMetadata(collectionName)
+	for _, stage := range ReviewStages {
+		path := fmt.Sprintf("%s%s/%s", m.stageDir(stage), uri, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return fmt.Sprintf("%s%s/%s", m.InProgress, uri, name)
+}
+
+// ReadArticle reads the article with the uri from the collection, at whichever review stage holds it.
+func ReadArticle(collectionName string, uri string) (*Article, error) {
+	path := ArticlePath(collectionName, uri)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, migration.Error{Message: "failed to read article json", OriginalErr: err, Params: log.Data{"path": path}}
+	}
+
+	a, err := UnmarshalPage(b)
+	if err != nil {
+		return nil, migration.Error{Message: "failed to unmarshal article json", OriginalErr: err, Params: log.Data{"path": path}}
+	}
+	return a, nil
+}
+
+// RemoveArticle removes the page with the uri from the inprogress directory of the collection - its data.json,
+// data_cy.json and figure files but not its child pages - and the directories left empty by removing it.
+func (c Collection) RemoveArticle(uri string) error {
+	dir := c.Metadata.InProgress + uri
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return migration.Error{Message: "failed to read article directory", OriginalErr: err, Params: log.Data{"collection": c.Name, "path": dir}}
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return migration.Error{Message: "failed to remove article file", OriginalErr: err, Params: log.Data{"collection": c.Name, "path": filepath.Join(dir, entry.Name())}}
+		}
+	}
+
+	// os.Remove fails on the first directory that is not empty
+	root := filepath.Clean(c.Metadata.InProgress)
+	for dir = filepath.Clean(dir); dir != root && os.Remove(dir) == nil; {
+		dir = filepath.Dir(dir)
+	}
 	return nil
 }
 
-func newCollectionID(collectionName string) string {
+func newCollectionID(collectionName string, idSeed string) string {
+	if CollectionIDNamespace != nil {
+		return fmt.Sprintf("%s-%s", collectionName, uuid.NewV5(*CollectionIDNamespace, idSeed).String())
+	}
 	return fmt.Sprintf("%s-%s", collectionName, uuid.NewV4().String())
 }
 
+// writeNewFile writes the file only if it does not already exist.
+func writeNewFile(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 func writeToFile(path string, b []byte) error {
 	if err := ioutil.WriteFile(path, b, 0644); err != nil {
 		return migration.Error{
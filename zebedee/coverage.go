@@ -0,0 +1,58 @@
+package zebedee
+
+import "strings"
+
+const (
+	defaultTemplate = ""
+	nullTemplate    = "null"
+	thumbnailIDKey  = "_thumbnail_id"
+)
+
+var (
+	// the wordpress shortcodes the converter replaces with ONS markdown
+	handledShortcodes = map[string]bool{
+		"iframe":      true,
+		"footnote":    true,
+		"explanation": true,
+	}
+
+	// the attributes of each element the converter keeps
+	handledAttributes = map[string]bool{
+		OpenATag + " " + hrefTag: true,
+		"img src":                true,
+	}
+)
+
+// HandlesElement returns true if the converter translates the html element to ONS markdown, the content of any other
+// element is kept as plain text.
+func HandlesElement(name string) bool {
+	if name == OpenATag || name == "img" {
+		return true
+	}
+	_, opens := openPlaceholders[name]
+	_, closes := closePlaceholders[name]
+	return opens || closes
+}
+
+// HandlesAttribute returns true if the converter keeps the attribute of the html element.
+func HandlesAttribute(element string, attr string) bool {
+	return handledAttributes[element+" "+attr]
+}
+
+// HandlesShortcode returns true if the converter replaces the wordpress shortcode.
+func HandlesShortcode(name string) bool {
+	return handledShortcodes[name]
+}
+
+// HandlesMetaKey returns true if the wordpress post meta key is migrated. The thumbnail is read but not migrated, the
+// article imageUri is always empty.
+func HandlesMetaKey(key string) bool {
+	return moreInfoRX.MatchString(key)
+}
+
+// HandlesTemplate returns true if the visual post template is migrated - every post is migrated as a standard article
+// so only posts using the default template keep their layout.
+func HandlesTemplate(template string) bool {
+	template = strings.TrimSpace(template)
+	return template == defaultTemplate || template == nullTemplate
+}
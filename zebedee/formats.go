@@ -22,8 +22,12 @@ const (
 	onsPulloutBoxOpenTag    = "<ons-box align=\"full\">"
 	onsPulloutBoxCloseTag   = "</ons-box>"
 	imageFormat             = "<img src=\"%s\"/>"
-	moreInfoLinkRXPtn       = "^more_information_\\d+_url$"
-	moreInfoTitleRXPtn       = "^more_information_\\d+_link_title$"
+	moreInfoRXPtn           = "^more_information_(\\d+)_(url|link_title|link_description)$"
+	moreInfoURL             = "url"
+	moreInfoTitle           = "link_title"
+	moreInfoDescription     = "link_description"
+	shortcodeRXPtn          = "\\[/?[a-z_]+(\\s[^\\]]*)?\\]"
+	markdownLinkRefRXPtn    = "^\\[\\d+\\]"
 )
 
 var (
@@ -32,8 +36,9 @@ var (
 	wpIFrameOpenRX    = regexp.MustCompile(iFrameOpenRXPtn)
 	explanationRX     = regexp.MustCompile(explanationRXPtn)
 	explanationOpenRX = regexp.MustCompile(explanationOpenTagRxPtn)
-	moreInfoURLRX     = regexp.MustCompile(moreInfoLinkRXPtn)
-	moreInfoTitleRX   = regexp.MustCompile(moreInfoTitleRXPtn)
+	moreInfoRX        = regexp.MustCompile(moreInfoRXPtn)
+	shortcodeRX       = regexp.MustCompile(shortcodeRXPtn)
+	markdownLinkRefRX = regexp.MustCompile(markdownLinkRefRXPtn)
 
 	openPlaceholders = map[string]func(string) string{
 		"h1": func(body string) string {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"golang.org/x/net/html"
+	"sort"
 )
 
 type Href struct {
@@ -102,8 +103,15 @@ htmlTokenizer:
 		markdownBody += linksFooter
 	}
 
-	for placeHolder, val := range onsMarkdown {
-		markdownBody = strings.Replace(markdownBody, placeHolder, val, -1)
+	// replace the placeholders in a fixed order so the output is the same every run
+	placeholders := make([]string, 0, len(onsMarkdown))
+	for placeHolder := range onsMarkdown {
+		placeholders = append(placeholders, placeHolder)
+	}
+	sort.Strings(placeholders)
+
+	for _, placeHolder := range placeholders {
+		markdownBody = strings.Replace(markdownBody, placeHolder, onsMarkdown[placeHolder], -1)
 	}
 
 	return markdownBody, nil
@@ -0,0 +1,66 @@
+package zebedee
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/config"
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/mmcdole/gofeed"
+)
+
+// getKeywords merges the mapping keywords with the wordpress tags of the visual post. Each term is passed through the
+// controlled vocabulary, duplicates are removed ignoring case and the list is cut to the maximum number of keywords.
+func getKeywords(vocab *config.Vocabulary, details *migration.Article, visualItem *gofeed.Item) ([]string, []string) {
+	keywords := make([]string, 0)
+	warnings := make([]string, 0)
+	seen := make(map[string]bool)
+
+	add := func(term string, strict bool) {
+		keyword, ok := vocab.GetKeyword(term)
+		if !ok {
+			if strict {
+				return
+			}
+			keyword = strings.TrimSpace(term)
+		}
+
+		key := strings.ToLower(keyword)
+		if keyword == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		keywords = append(keywords, keyword)
+	}
+
+	// the mapping keywords were chosen for the migration so are always used, tags only if the vocabulary allows them.
+	for _, term := range details.Keywords {
+		add(term, false)
+	}
+	for _, tag := range migration.GetCategories(visualItem, migration.TagDomain) {
+		add(tag, vocab.Strict)
+	}
+
+	if vocab.MaxKeywords > 0 && len(keywords) > vocab.MaxKeywords {
+		dropped := keywords[vocab.MaxKeywords:]
+		warnings = append(warnings, fmt.Sprintf("too many keywords, dropped: %s", strings.Join(dropped, ", ")))
+		keywords = keywords[:vocab.MaxKeywords]
+	}
+	return keywords, warnings
+}
+
+// getTopics returns the taxonomy topics for the wordpress categories of the visual post.
+func getTopics(vocab *config.Vocabulary, visualItem *gofeed.Item) []*RelatedLink {
+	topics := make([]*RelatedLink, 0)
+	seen := make(map[string]bool)
+
+	for _, category := range migration.GetCategories(visualItem, migration.CategoryDomain) {
+		uri, ok := vocab.GetTopic(category)
+		if !ok || seen[uri] {
+			continue
+		}
+		seen[uri] = true
+		topics = append(topics, &RelatedLink{URI: uri})
+	}
+	return topics
+}
@@ -0,0 +1,124 @@
+package zebedee
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const lockFileName = "visual-migration.lock"
+
+// Lock an advisory lock on the collections directory held for the duration of a run, so two runs in the same
+// container cannot create the same collections or write the same results file.
+type Lock struct {
+	Host        string    `json:"host"`
+	PID         int       `json:"pid"`
+	Started     time.Time `json:"started"`
+	Command     string    `json:"command"`
+	Rows        string    `json:"rows,omitempty"`
+	ResultsFile string    `json:"resultsFile,omitempty"`
+	path        string
+}
+
+// LockPath returns the path of the lock file in the collections directory.
+func LockPath() string {
+	return filepath.Join(CollectionsRoot, lockFileName)
+}
+
+// AcquireLock creates the lock file recording this run, returning an error describing the run holding the lock if it
+// already exists.
+func AcquireLock(command string, rows string, resultsFile string) (*Lock, error) {
+	host, _ := os.Hostname()
+	l := &Lock{
+		Host:        host,
+		PID:         os.Getpid(),
+		Started:     time.Now(),
+		Command:     command,
+		Rows:        rows,
+		ResultsFile: resultsFile,
+		path:        LockPath(),
+	}
+
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return nil, migration.Error{Message: "failed to marshal lock", OriginalErr: err, Params: nil}
+	}
+
+	// creating the file exclusively is atomic, only one of two runs starting together can succeed
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return nil, heldError(l.path)
+	}
+	if err != nil {
+		return nil, migration.Error{Message: "failed to create lock file", OriginalErr: err, Params: log.Data{"path": l.path}}
+	}
+	defer f.Close()
+
+	if _, err := f.Write(b); err != nil {
+		os.Remove(l.path)
+		return nil, migration.Error{Message: "failed to write lock file", OriginalErr: err, Params: log.Data{"path": l.path}}
+	}
+
+	log.Info("collections directory locked", log.Data{"path": l.path, "command": command, "rows": rows})
+	return l, nil
+}
+
+// ForceUnlock removes the lock left by a run that did not release it, logging the run that held it. Only use it once
+// the run holding the lock is known to have ended.
+func ForceUnlock() error {
+	path := LockPath()
+	data := log.Data{"path": path}
+	if held, err := readLock(path); err == nil {
+		data["host"], data["pid"], data["started"], data["command"], data["rows"] = held.Host, held.PID, held.Started, held.Command, held.Rows
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return migration.Error{Message: "failed to remove lock file", OriginalErr: err, Params: data}
+	}
+	log.Info("removed collections directory lock", data)
+	return nil
+}
+
+// Release removes the lock file.
+func (l *Lock) Release() error {
+	if err := os.Remove(l.path); err != nil {
+		return migration.Error{Message: "failed to remove lock file", OriginalErr: err, Params: log.Data{"path": l.path}}
+	}
+	log.Info("collections directory unlocked", log.Data{"path": l.path})
+	return nil
+}
+
+func readLock(path string) (*Lock, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var l Lock
+	if err := json.Unmarshal(b, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func heldError(path string) error {
+	data := log.Data{"path": path}
+	held, err := readLock(path)
+	if err != nil {
+		return migration.Error{Message: "collections directory is locked by another run, use -force-unlock if it has ended", OriginalErr: err, Params: data}
+	}
+
+	data["host"], data["pid"], data["started"], data["command"] = held.Host, held.PID, held.Started, held.Command
+	if held.Rows != "" {
+		data["rows"] = held.Rows
+	}
+	if held.ResultsFile != "" {
+		data["results_file"] = held.ResultsFile
+	}
+	return migration.Error{Message: "collections directory is locked by another run, use -force-unlock if it has ended", OriginalErr: nil, Params: data}
+}
@@ -0,0 +1,263 @@
+package zebedee
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	// CompendiumLandingPage the page listing the chapters of a compendium, the parent of each chapter.
+	CompendiumLandingPage = "compendium_landing_page"
+
+	sectionSeparator = "\n\n"
+)
+
+// StaticPage the data.json of a static page, a single markdown body e.g. a short explainer.
+type StaticPage struct {
+	Type        string         `json:"type"`
+	URI         string         `json:"uri"`
+	Description Description    `json:"description"`
+	Markdown    string         `json:"markdown"`
+	Links       []*RelatedLink `json:"links"`
+	Downloads   []*Download    `json:"downloads"`
+}
+
+// StaticArticle the data.json of a static article, an article without release dates or versions.
+type StaticArticle struct {
+	Type        string             `json:"type"`
+	URI         string             `json:"uri"`
+	Description Description        `json:"description"`
+	Sections    []*MarkdownSection `json:"sections"`
+	Accordion   []*MarkdownSection `json:"accordion"`
+	Links       []*RelatedLink     `json:"links"`
+	Downloads   []*Download        `json:"downloads"`
+	Charts      []*Figure          `json:"charts"`
+	Tables      []*Figure          `json:"tables"`
+	Images      []*Figure          `json:"images"`
+	Equations   []*Figure          `json:"equations"`
+	Alerts      []*Alert           `json:"alerts"`
+}
+
+// CompendiumChapter the data.json of a chapter of a compendium, the uri of its CompendiumLanding is its parent.
+type CompendiumChapter struct {
+	Type                      string             `json:"type"`
+	URI                       string             `json:"uri"`
+	Description               Description        `json:"description"`
+	Sections                  []*MarkdownSection `json:"sections"`
+	Accordion                 []*MarkdownSection `json:"accordion"`
+	PDFTable                  []*Figure          `json:"pdfTable"`
+	RelatedData               []*RelatedLink     `json:"relatedData"`
+	RelatedDocs               []*RelatedLink     `json:"relatedDocuments"`
+	Charts                    []*Figure          `json:"charts"`
+	Tables                    []*Figure          `json:"tables"`
+	Images                    []*Figure          `json:"images"`
+	Equations                 []*Figure          `json:"equations"`
+	Links                     []*RelatedLink     `json:"links"`
+	RelatedMethodology        []*RelatedLink     `json:"relatedMethodology"`
+	RelatedMethodologyArticle []*RelatedLink     `json:"relatedMethodologyArticle"`
+	Versions                  []*Version         `json:"versions"`
+	Alerts                    []*Alert           `json:"alerts"`
+	Topics                    []*RelatedLink     `json:"topics"`
+}
+
+// CompendiumLanding the data.json of a compendium landing page listing its chapters.
+type CompendiumLanding struct {
+	Type                      string         `json:"type"`
+	URI                       string         `json:"uri"`
+	Description               Description    `json:"description"`
+	Chapters                  []*RelatedLink `json:"chapters"`
+	Datasets                  []*RelatedLink `json:"datasets"`
+	RelatedMethodology        []*RelatedLink `json:"relatedMethodology"`
+	RelatedMethodologyArticle []*RelatedLink `json:"relatedMethodologyArticle"`
+	Alerts                    []*Alert       `json:"alerts"`
+}
+
+// Download a file offered for download from the page.
+type Download struct {
+	Title string `json:"title"`
+	File  string `json:"file"`
+}
+
+// Page returns the zebedee data.json structure of the article's page type.
+func (a *Article) Page() interface{} {
+	switch a.Type {
+	case migration.PageStaticPage:
+		markdown := make([]string, 0, len(a.Sections))
+		for _, s := range a.Sections {
+			markdown = append(markdown, s.Markdown)
+		}
+		return &StaticPage{
+			Type:        a.Type,
+			URI:         a.URI,
+			Description: a.Description,
+			Markdown:    strings.Join(markdown, sectionSeparator),
+			Links:       a.Links,
+			Downloads:   []*Download{},
+		}
+
+	case migration.PageStaticArticle:
+		return &StaticArticle{
+			Type:        a.Type,
+			URI:         a.URI,
+			Description: a.Description,
+			Sections:    a.Sections,
+			Accordion:   a.Accordion,
+			Links:       a.Links,
+			Downloads:   []*Download{},
+			Charts:      a.Charts,
+			Tables:      a.Tables,
+			Images:      a.Images,
+			Equations:   a.Equations,
+			Alerts:      a.Alerts,
+		}
+
+	case migration.PageCompendiumChapter:
+		return &CompendiumChapter{
+			Type:                      a.Type,
+			URI:                       a.URI,
+			Description:               a.Description,
+			Sections:                  a.Sections,
+			Accordion:                 a.Accordion,
+			PDFTable:                  a.PDFTable,
+			RelatedData:               a.RelatedData,
+			RelatedDocs:               a.RelatedDocs,
+			Charts:                    a.Charts,
+			Tables:                    a.Tables,
+			Images:                    a.Images,
+			Equations:                 a.Equations,
+			Links:                     a.Links,
+			RelatedMethodology:        a.RelatedMethodology,
+			RelatedMethodologyArticle: a.RelatedMethodologyArticle,
+			Versions:                  a.Versions,
+			Alerts:                    a.Alerts,
+			Topics:                    a.Topics,
+		}
+
+	default:
+		return a
+	}
+}
+
+// newPage returns an empty structure for the data.json of the page type.
+func newPage(pageType string) interface{} {
+	switch pageType {
+	case migration.PageStaticPage:
+		return &StaticPage{}
+	case migration.PageStaticArticle:
+		return &StaticArticle{}
+	case migration.PageCompendiumChapter:
+		return &CompendiumChapter{}
+	case CompendiumLandingPage:
+		return &CompendiumLanding{}
+	default:
+		return &Article{}
+	}
+}
+
+// PageType returns the type of the page json.
+func PageType(b []byte) (string, error) {
+	var page struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(b, &page); err != nil {
+		return "", err
+	}
+	return page.Type, nil
+}
+
+// UnmarshalPage reads the data.json of an article, static page, static article or compendium chapter into an article.
+func UnmarshalPage(b []byte) (*Article, error) {
+	pageType, err := PageType(b)
+	if err != nil {
+		return nil, err
+	}
+
+	page := newPage(pageType)
+	if err := json.Unmarshal(b, page); err != nil {
+		return nil, err
+	}
+
+	switch p := page.(type) {
+	case *StaticPage:
+		return &Article{Type: p.Type, URI: p.URI, Description: p.Description, Sections: []*MarkdownSection{{Markdown: p.Markdown}}, Links: p.Links}, nil
+	case *StaticArticle:
+		return &Article{Type: p.Type, URI: p.URI, Description: p.Description, Sections: p.Sections, Accordion: p.Accordion, Links: p.Links,
+			Charts: p.Charts, Tables: p.Tables, Images: p.Images, Equations: p.Equations, Alerts: p.Alerts}, nil
+	case *CompendiumChapter:
+		return &Article{Type: p.Type, URI: p.URI, Description: p.Description, Sections: p.Sections, Accordion: p.Accordion,
+			PDFTable: p.PDFTable, RelatedData: p.RelatedData, RelatedDocs: p.RelatedDocs, Charts: p.Charts, Tables: p.Tables,
+			Images: p.Images, Equations: p.Equations, Links: p.Links, RelatedMethodology: p.RelatedMethodology,
+			RelatedMethodologyArticle: p.RelatedMethodologyArticle, Versions: p.Versions, Alerts: p.Alerts, Topics: p.Topics}, nil
+	case *Article:
+		return p, nil
+	default:
+		return nil, migration.Error{Message: "page is not an article", OriginalErr: nil, Params: log.Data{"type": pageType}}
+	}
+}
+
+// CompendiumURI returns the uri of the landing page of a compendium chapter - the chapter's parent.
+func CompendiumURI(chapterURI string) string {
+	return chapterURI[:strings.LastIndex(chapterURI, "/")]
+}
+
+// addToCompendium adds the chapter to the landing page of its compendium in the collection, creating the landing page
+// if this is the first chapter of the compendium in the collection.
+func (c Collection) addToCompendium(chapter *Article) error {
+	uri := CompendiumURI(chapter.URI)
+	path := c.Metadata.InProgress + uri + "/" + dataJSON
+
+	landing := &CompendiumLanding{
+		Type: CompendiumLandingPage,
+		URI:  uri,
+		Description: Description{
+			Title:       chapter.CompendiumTitle,
+			Keywords:    chapter.Description.Keywords,
+			Contact:     chapter.Description.Contact,
+			ReleaseDate: chapter.Description.ReleaseDate,
+			Edition:     uri[strings.LastIndex(uri, "/")+1:],
+		},
+		Chapters:                  []*RelatedLink{},
+		Datasets:                  []*RelatedLink{},
+		RelatedMethodology:        []*RelatedLink{},
+		RelatedMethodologyArticle: []*RelatedLink{},
+		Alerts:                    []*Alert{},
+	}
+
+	// the landing page in the collection, or the published landing page if the compendium is already published
+	existing := path
+	if _, err := os.Stat(path); err != nil && IsPublished(uri) {
+		existing = filepath.Join(PublishedRoot, uri, dataJSON)
+	}
+	if b, err := ioutil.ReadFile(existing); err == nil {
+		if err := json.Unmarshal(b, landing); err != nil {
+			return migration.Error{Message: "failed to unmarshal compendium landing page", OriginalErr: err, Params: log.Data{"path": existing}}
+		}
+	}
+
+	for _, l := range landing.Chapters {
+		if l.URI == chapter.URI {
+			return nil
+		}
+	}
+	landing.Chapters = append(landing.Chapters, &RelatedLink{Title: chapter.Description.Title, URI: chapter.URI})
+
+	b, err := json.MarshalIndent(landing, "", "	")
+	if err != nil {
+		return migration.Error{Message: "failed to marshal compendium landing page", OriginalErr: err, Params: log.Data{"path": path}}
+	}
+	if problems := ValidatePage(CompendiumLandingPage, b); len(problems) > 0 {
+		return migration.Error{Message: "compendium landing page does not match its schema", OriginalErr: nil, Params: log.Data{"path": path, "problems": problems}}
+	}
+
+	log.Info("adding chapter to compendium landing page", log.Data{"collection": c.Name, "compendium": uri, "chapter": chapter.URI})
+	if err := os.MkdirAll(c.Metadata.InProgress+uri, 0755); err != nil {
+		return migration.Error{Message: "error making compendium landing page directories", OriginalErr: err, Params: log.Data{"collection": c.Name, "path": path}}
+	}
+	return writeToFile(path, b)
+}
@@ -0,0 +1,339 @@
+package zebedee
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/dp-visual-ons-migration/schema"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+// Schemas the JSON Schema of each page type, pages are not validated if it is nil.
+var Schemas map[string]*schema.Schema
+
+// ValidatePage validates the page json against the schema of its page type, returning a message for each invalid
+// field.
+func ValidatePage(pageType string, b []byte) []string {
+	if Schemas == nil {
+		return nil
+	}
+	s, ok := Schemas[pageType]
+	if !ok {
+		return []string{fmt.Sprintf("type: there is no schema for page type %q", pageType)}
+	}
+	return s.Validate(b)
+}
+
+// VerifyArticle re-reads the collection json and the data.json of the article with the uri as zebedee would, checking
+// they match the schemas, the directory layout of the collection and that the article uri matches its path. The
+// data_cy.json of the Welsh version of the article is checked in the same way if there is one.
+func (c Collection) VerifyArticle(uri string) error {
+	problems := verifyCollectionJSON(c.Metadata, c.Name)
+	problems = append(problems, verifyLayout(c.Metadata)...)
+	problems = append(problems, verifyPage(c.Metadata, c.Metadata.InProgress, uri)...)
+
+	if len(problems) > 0 {
+		return migration.Error{
+			Message:     "collection failed verification after writing article",
+			OriginalErr: nil,
+			Params:      log.Data{"collection": c.Name, "uri": uri, "problems": problems},
+		}
+	}
+	return nil
+}
+
+// VerifyCollection checks an existing collection as VerifyArticle does for every page in its inprogress, complete and
+// reviewed directories, returning the problems found. A page may only be at one review stage, and the pages in complete
+// and reviewed must have the events of being moved there.
+func VerifyCollection(name string) []string {
+	metadata := newCollectionMetadata(name)
+	problems := verifyCollectionJSON(metadata, name)
+	problems = append(problems, verifyLayout(metadata)...)
+
+	var c Collection
+	if b, err := ioutil.ReadFile(metadata.CollectionJSON); err == nil {
+		json.Unmarshal(b, &c)
+	}
+
+	stages := make(map[string]string)
+	for _, stage := range ReviewStages {
+		dir := metadata.stageDir(stage)
+		for _, uri := range pageURIs(dir, &problems) {
+			if previous, ok := stages[uri]; ok {
+				problems = append(problems, fmt.Sprintf("page %s is in both %s and %s", uri, previous, stage))
+				continue
+			}
+			stages[uri] = stage
+
+			problems = append(problems, verifyPage(metadata, dir, uri)...)
+			problems = append(problems, verifyEvents(c.EventsByURI[uri+"/"+dataJSON], stage, uri)...)
+		}
+	}
+
+	if len(stages) == 0 && len(problems) == 0 {
+		problems = append(problems, "collection has no articles")
+	}
+	return problems
+}
+
+// pageURIs returns the uris of the pages in the review stage directory, adding a problem for any unexpected file.
+func pageURIs(dir string, problems *[]string) []string {
+	uris := make([]string, 0)
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		// previous versions are copies of published pages, not migrated content
+		if info.IsDir() {
+			if info.Name() == previousDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch info.Name() {
+		case dataJSONWelsh:
+			if _, err := os.Stat(filepath.Join(filepath.Dir(path), dataJSON)); err != nil {
+				*problems = append(*problems, fmt.Sprintf("welsh page %s has no english %s", path, dataJSON))
+			}
+			return nil
+		case dataJSON:
+		default:
+			*problems = append(*problems, fmt.Sprintf("unexpected file %s in %s", path, filepath.Base(dir)))
+			return nil
+		}
+		uris = append(uris, contentURI(dir, filepath.Dir(path)))
+		return nil
+	})
+	return uris
+}
+
+// contentURI returns the uri of the path in the content directory e.g. /economy/.../data.json, the directory may be
+// given with a trailing slash.
+func contentURI(dir string, path string) string {
+	rel, err := filepath.Rel(filepath.Clean(dir), path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return "/" + filepath.ToSlash(rel)
+}
+
+// verifyEvents checks a page moved to complete or reviewed has the events of each move.
+func verifyEvents(events []*Event, stage string, uri string) []string {
+	required := map[string][]string{
+		StageComplete: {EventCompleted},
+		StageReviewed: {EventCompleted, EventReviewed},
+	}[stage]
+
+	problems := make([]string, 0)
+	for _, eventType := range required {
+		found := false
+		for _, e := range events {
+			found = found || e.Type == eventType
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("page %s is %s but has no %s event", uri, stage, eventType))
+		}
+	}
+	return problems
+}
+
+// ListCollections returns the names of the collections in the collections directory - the directories with a
+// collection json alongside them.
+func ListCollections() ([]string, error) {
+	entries, err := ioutil.ReadDir(CollectionsRoot)
+	if err != nil {
+		return nil, migration.Error{Message: "failed to read collections directory", OriginalErr: err, Params: log.Data{"path": CollectionsRoot}}
+	}
+
+	names := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(newCollectionMetadata(entry.Name()).CollectionJSON); err == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func verifyCollectionJSON(metadata *CollectionMetadata, name string) []string {
+	var c Collection
+	problems := readStrict(metadata.CollectionJSON, &c)
+	if len(problems) > 0 {
+		return problems
+	}
+
+	if c.Name != name {
+		problems = append(problems, fmt.Sprintf("collection json name %q does not match the collection directory %q", c.Name, name))
+	}
+	if !strings.HasPrefix(c.ID, name+"-") {
+		problems = append(problems, fmt.Sprintf("collection id %q is not prefixed with the collection name", c.ID))
+	}
+	if c.ApprovalStatus != approvalStatus {
+		problems = append(problems, fmt.Sprintf("collection approval status is %q, expected %q", c.ApprovalStatus, approvalStatus))
+	}
+
+	switch c.Type {
+	case ManualCollection:
+		if c.PublishDate != "" {
+			problems = append(problems, "manual collection has a publish date")
+		}
+	case ScheduledCollection:
+		if _, err := time.Parse(publishDateFormat, c.PublishDate); err != nil {
+			problems = append(problems, fmt.Sprintf("scheduled collection publish date %q is invalid", c.PublishDate))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("collection type %q is not %s or %s", c.Type, ManualCollection, ScheduledCollection))
+	}
+	return problems
+}
+
+// verifyLayout checks the inprogress, complete and reviewed directories exist.
+func verifyLayout(metadata *CollectionMetadata) []string {
+	problems := make([]string, 0)
+	for _, stage := range ReviewStages {
+		if dir := metadata.stageDir(stage); !isDir(dir) {
+			problems = append(problems, fmt.Sprintf("collection directory %s is missing", dir))
+		}
+	}
+	return problems
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// verifyPage checks the data.json of the page with the uri in the review stage directory, and its data_cy.json if it
+// has a Welsh version.
+func verifyPage(metadata *CollectionMetadata, dir string, uri string) []string {
+	problems := verifyPageJSON(metadata, dir, uri, dataJSON)
+	if _, err := os.Stat(dir + uri + "/" + dataJSONWelsh); err == nil {
+		problems = append(problems, verifyPageJSON(metadata, dir, uri, dataJSONWelsh)...)
+	}
+	return problems
+}
+
+// verifyPageJSON checks the page json file against the structure and schema of its page type.
+func verifyPageJSON(metadata *CollectionMetadata, dir string, uri string, filename string) []string {
+	path := dir + uri + "/" + filename
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %s", path, err)}
+	}
+	pageType, err := PageType(b)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %s", path, err)}
+	}
+	if pageType != CompendiumLandingPage && !isPageType(pageType) {
+		return []string{fmt.Sprintf("%s: unexpected page type %q", path, pageType)}
+	}
+
+	page := newPage(pageType)
+	problems := readStrict(path, page)
+	if len(problems) > 0 {
+		return problems
+	}
+	for _, p := range ValidatePage(pageType, b) {
+		problems = append(problems, fmt.Sprintf("%s: %s", path, p))
+	}
+
+	var common struct {
+		URI         string      `json:"uri"`
+		Description Description `json:"description"`
+		Versions    []*Version  `json:"versions"`
+	}
+	json.Unmarshal(b, &common)
+
+	if common.URI != uri {
+		problems = append(problems, fmt.Sprintf("%s: uri %q does not match its path %q", path, common.URI, uri))
+	}
+	if common.Description.Title == "" {
+		problems = append(problems, fmt.Sprintf("%s: description has no title", path))
+	}
+	if pageType != migration.PageStaticPage && pageType != migration.PageStaticArticle {
+		if _, err := time.Parse(releaseDateFormat, common.Description.ReleaseDate); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: release date %q is invalid", path, common.Description.ReleaseDate))
+		}
+	}
+	for _, v := range common.Versions {
+		if !metadata.hasPage(v.URI) && !IsPublished(v.URI) {
+			problems = append(problems, fmt.Sprintf("%s: version %q is not in the collection or published content", path, v.URI))
+		}
+	}
+	return problems
+}
+
+// hasPage returns true if the page with the uri is in the collection at any review stage.
+func (m *CollectionMetadata) hasPage(uri string) bool {
+	for _, stage := range ReviewStages {
+		if _, err := os.Stat(m.stageDir(stage) + uri + "/" + dataJSON); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isPageType(pageType string) bool {
+	for _, t := range migration.PageTypes {
+		if t == pageType {
+			return true
+		}
+	}
+	return false
+}
+
+// readStrict unmarshals the json file, reporting any field the schema does not have and any field it requires that is
+// missing.
+func readStrict(path string, v interface{}) []string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %s", path, err)}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return []string{fmt.Sprintf("%s: %s", path, err)}
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return []string{fmt.Sprintf("%s: %s", path, err)}
+	}
+
+	problems := make([]string, 0)
+	for _, key := range requiredFields(v) {
+		if _, ok := fields[key]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: required field %q is missing", path, key))
+		}
+	}
+	return problems
+}
+
+// requiredFields returns the json keys of the exported struct fields that are not omitempty.
+func requiredFields(v interface{}) []string {
+	t := reflect.TypeOf(v).Elem()
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		if f.PkgPath != "" || tag[0] == "-" || tag[0] == "" || (len(tag) > 1 && tag[1] == "omitempty") {
+			continue
+		}
+		keys = append(keys, tag[0])
+	}
+	return keys
+}
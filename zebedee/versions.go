@@ -0,0 +1,178 @@
+package zebedee
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	previousDir = "previous"
+
+	NewVersionWarning    = "the ONS uri is already published, the article will be written as a new version of it"
+	DefaultVersionNotice = "This page has been updated with content migrated from visual.ons.gov.uk."
+)
+
+var (
+	// PublishedRoot the local copy of the published content. An article migrated to a URI that is already published is
+	// written as a new version of the published page. Nothing is versioned if it is empty.
+	PublishedRoot = ""
+
+	// VersionNotice the correction notice of the version entry added when a published page is replaced.
+	VersionNotice = DefaultVersionNotice
+)
+
+// publishedPage the fields of a published data.json needed to version it.
+type publishedPage struct {
+	Type     string     `json:"type"`
+	Versions []*Version `json:"versions"`
+}
+
+// IsPublished returns true if there is a page with the uri in the published content.
+func IsPublished(uri string) bool {
+	if PublishedRoot == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(PublishedRoot, uri, dataJSON))
+	return err == nil
+}
+
+// CheckPublished warns if the article replaces a published page, returning an error if the published page can not be
+// versioned - it is a different page type, or a static page which has no versions.
+func CheckPublished(a *Article) error {
+	if !IsPublished(a.URI) {
+		return nil
+	}
+
+	published, err := readPublishedPage(a.URI)
+	if err != nil {
+		return err
+	}
+	if published.Type != a.Type {
+		return migration.Error{
+			Message: "the ONS uri is already published as a different page type",
+			Params:  log.Data{"uri": a.URI, "published_type": published.Type, "type": a.Type},
+		}
+	}
+	if a.Type == migration.PageStaticPage || a.Type == migration.PageStaticArticle {
+		return migration.Error{
+			Message: "the ONS uri is already published and static pages can not be versioned",
+			Params:  log.Data{"uri": a.URI, "type": a.Type},
+		}
+	}
+
+	a.Warnings = append(a.Warnings, NewVersionWarning)
+	return nil
+}
+
+// addVersion copies the published page with the article's uri into previous/v<n> in the collection and adds it to
+// the versions of the article, after the versions of the published page. The update date of the version is the
+// publish date of the collection, or now for a manual collection.
+func (c Collection) addVersion(a *Article) error {
+	published, err := readPublishedPage(a.URI)
+	if err != nil {
+		return err
+	}
+
+	n := len(published.Versions) + 1
+	for IsPublished(versionURI(a.URI, n)) {
+		n++
+	}
+	uri := versionURI(a.URI, n)
+
+	log.Info("versioning published page", log.Data{"collection": c.Name, "uri": a.URI, "version": uri})
+	if err := copyPublishedPage(a.URI, c.Metadata.InProgress+uri, uri); err != nil {
+		return err
+	}
+
+	updateDate := c.PublishDate
+	if updateDate == "" {
+		updateDate = FormatReleaseDate(Now())
+	}
+
+	a.Versions = append(published.Versions, &Version{
+		URI:              uri,
+		UpdateDate:       updateDate,
+		CorrectionNotice: VersionNotice,
+		Label:            fmt.Sprintf("v%d", n),
+	})
+	return nil
+}
+
+func readPublishedPage(uri string) (*publishedPage, error) {
+	path := filepath.Join(PublishedRoot, uri, dataJSON)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, migration.Error{Message: "failed to read published page", OriginalErr: err, Params: log.Data{"path": path}}
+	}
+
+	var p publishedPage
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, migration.Error{Message: "failed to unmarshal published page", OriginalErr: err, Params: log.Data{"path": path}}
+	}
+	if p.Versions == nil {
+		p.Versions = []*Version{}
+	}
+	return &p, nil
+}
+
+// copyPublishedPage copies the files of the published page - its data.json, data_cy.json and the files of its
+// figures, but not its child pages - into the directory, setting the uri of the page json to the uri of the version.
+func copyPublishedPage(uri string, dir string, versionURI string) error {
+	src := filepath.Join(PublishedRoot, uri)
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return migration.Error{Message: "failed to read published page directory", OriginalErr: err, Params: log.Data{"path": src}}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return migration.Error{Message: "error making version directories", OriginalErr: err, Params: log.Data{"path": dir}}
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(src, entry.Name())
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return migration.Error{Message: "failed to read published page file", OriginalErr: err, Params: log.Data{"path": path}}
+		}
+
+		if entry.Name() == dataJSON || entry.Name() == dataJSONWelsh {
+			if b, err = setURI(b, versionURI); err != nil {
+				return migration.Error{Message: "failed to set uri of published page version", OriginalErr: err, Params: log.Data{"path": path}}
+			}
+		}
+
+		if err := writeToFile(filepath.Join(dir, entry.Name()), b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setURI returns the page json with its uri replaced.
+func setURI(b []byte, uri string) ([]byte, error) {
+	var page map[string]json.RawMessage
+	if err := json.Unmarshal(b, &page); err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(uri)
+	if err != nil {
+		return nil, err
+	}
+	page["uri"] = value
+	return json.MarshalIndent(page, "", "	")
+}
+
+func versionURI(uri string, n int) string {
+	return fmt.Sprintf("%s/%s/v%d", uri, previousDir, n)
+}
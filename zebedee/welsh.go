@@ -0,0 +1,83 @@
+package zebedee
+
+import (
+	"io/ioutil"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	// the data.json of the Welsh version of a page, zebedee falls back to the English data.json if it does not exist
+	dataJSONWelsh = "data_cy.json"
+
+	NoWelshTranslationWarning = "no welsh translation for post"
+)
+
+// CreateWelshArticle returns the Welsh version of the English article from the translation of its visual post. The
+// translation replaces the title, summary, keywords (if it has any) and body of the English article, everything else is
+// copied from it. The body must be converted with ConvertToONSFormat.
+func CreateWelshArticle(english *Article, t *migration.Translation) *Article {
+	cy := *english
+
+	cy.Description = english.Description
+	cy.Description.Title = t.Title
+	cy.Description.Summary = t.Summary
+	if len(t.Keywords) > 0 {
+		cy.Description.Keywords = t.Keywords
+	}
+
+	cy.Sections = []*MarkdownSection{{Markdown: t.Content}}
+	cy.Links = make([]*RelatedLink, 0, len(english.Links))
+	for _, l := range english.Links {
+		link := *l
+		cy.Links = append(cy.Links, &link)
+	}
+	cy.Warnings = []string{}
+	return &cy
+}
+
+// AddWelshArticle writes the Welsh version of an article added to the collection alongside its data.json. The Welsh
+// version has the same versions as the English article.
+func (c *Collection) AddWelshArticle(a *Article) error {
+	dir := c.Metadata.InProgress + a.URI
+	path := dir + "/" + dataJSONWelsh
+
+	english, err := ioutil.ReadFile(dir + "/" + dataJSON)
+	if err != nil {
+		return migration.Error{Message: "welsh article has no english article", OriginalErr: err, Params: log.Data{"collection": c.Name, "path": path}}
+	}
+	englishArticle, err := UnmarshalPage(english)
+	if err != nil {
+		return migration.Error{Message: "failed to unmarshal english article", OriginalErr: err, Params: log.Data{"collection": c.Name, "path": path}}
+	}
+	a.Versions = englishArticle.Versions
+
+	b, err := MarshalArticle(a)
+	if err != nil {
+		return err
+	}
+
+	if problems := ValidatePage(a.Type, b); len(problems) > 0 {
+		return migration.Error{
+			Message:     "welsh article does not match the schema of its page type",
+			OriginalErr: nil,
+			Params:      log.Data{"collection": c.Name, "path": path, "problems": problems},
+		}
+	}
+
+	if err := writeToFile(path, b); err != nil {
+		return migration.Error{
+			Message:     "failed to write welsh article json",
+			OriginalErr: err,
+			Params:      log.Data{"collection": c.Name, "path": path},
+		}
+	}
+	return c.recordCreated(a.URI + "/" + dataJSONWelsh)
+}
+
+// WelshArticlePath returns the path of the data_cy.json of the article with the uri in the collection, in the directory
+// of the review stage that holds it - inprogress if the collection does not have it.
+func WelshArticlePath(collectionName string, uri string) string {
+	return pagePath(collectionName, uri, dataJSONWelsh)
+}
@@ -0,0 +1,279 @@
+package zebedee
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-visual-ons-migration/migration"
+	"github.com/ONSdigital/go-ns/log"
+)
+
+const (
+	// the review stages of the content of a collection, in order - content is created in progress, completed by one
+	// user and reviewed by another
+	StageInProgress = inProgress
+	StageComplete   = complete
+	StageReviewed   = reviewed
+
+	// the events zebedee records when a collection or its content is created, and when content is completed and reviewed
+	EventCreated   = "CREATED"
+	EventCompleted = "COMPLETED"
+	EventReviewed  = "REVIEWED"
+)
+
+var (
+	// ReviewStages the review stages in order.
+	ReviewStages = []string{StageInProgress, StageComplete, StageReviewed}
+
+	// CompletedBy and ReviewedBy the zebedee users recorded as completing and reviewing migrated content. CompletedBy is
+	// also recorded as creating collections and their content, no events are recorded if it is not set.
+	CompletedBy = ""
+	ReviewedBy  = ""
+)
+
+// Event an entry in the history of a collection or of a file in the collection.
+type Event struct {
+	Date  string `json:"date"`
+	Type  string `json:"type"`
+	Email string `json:"email"`
+}
+
+// ReviewStageIndex returns the position of the stage in ReviewStages, -1 if it is not a review stage.
+func ReviewStageIndex(stage string) int {
+	for i, s := range ReviewStages {
+		if s == stage {
+			return i
+		}
+	}
+	return -1
+}
+
+// stageDir returns the directory of the collection holding content at the review stage.
+func (m *CollectionMetadata) stageDir(stage string) string {
+	switch stage {
+	case StageComplete:
+		return m.Complete
+	case StageReviewed:
+		return m.Reviewed
+	default:
+		return m.InProgress
+	}
+}
+
+// Promote advances the content of the collection to the review stage as zebedee would: content in progress is moved to
+// complete by CompletedBy, then content complete is moved to reviewed by ReviewedBy. Each move is recorded in the
+// collection events and the events of every moved page json. Content already at or past the stage is left where it is.
+func (c *Collection) Promote(stage string, at time.Time) error {
+	target := ReviewStageIndex(stage)
+	if target < 0 {
+		return migration.Error{Message: "unknown review stage", Params: log.Data{"collection": c.Name, "stage": stage}}
+	}
+
+	moved := false
+	for i := 1; i <= target; i++ {
+		event, email := EventCompleted, CompletedBy
+		if ReviewStages[i] == StageReviewed {
+			event, email = EventReviewed, ReviewedBy
+		}
+		if email == "" {
+			return migration.Error{Message: "no zebedee user configured to move content to the review stage", Params: log.Data{"collection": c.Name, "stage": ReviewStages[i]}}
+		}
+
+		uris, err := moveContent(c.Metadata.stageDir(ReviewStages[i-1]), c.Metadata.stageDir(ReviewStages[i]))
+		if err != nil {
+			return migration.Error{Message: "failed to move collection content", OriginalErr: err, Params: log.Data{"collection": c.Name, "stage": ReviewStages[i]}}
+		}
+		if len(uris) == 0 {
+			continue
+		}
+
+		c.addEvents(event, email, at, uris)
+		moved = true
+		log.Info("moved collection content", log.Data{"collection": c.Name, "stage": ReviewStages[i], "files": len(uris), "by": email})
+	}
+
+	if !moved {
+		return nil
+	}
+
+	b, err := MarshalCollection(c)
+	if err != nil {
+		return err
+	}
+	return writeToFile(c.Metadata.CollectionJSON, b)
+}
+
+// IsInProgress returns true if none of the content of the collection has been completed or reviewed.
+func (c *Collection) IsInProgress() bool {
+	for _, dir := range []string{c.Metadata.Complete, c.Metadata.Reviewed} {
+		if entries, err := ioutil.ReadDir(dir); err != nil || len(entries) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// StagePath returns the path of a file of the collection at the review stage that now holds it, for a path recorded
+// before Promote moved it. Paths outside the review stage directories, such as the collection json, are unchanged.
+func (c *Collection) StagePath(path string) string {
+	for _, from := range ReviewStages {
+		rel, err := filepath.Rel(filepath.Clean(c.Metadata.stageDir(from)), path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		for _, to := range ReviewStages {
+			moved := filepath.Join(c.Metadata.stageDir(to), rel)
+			if _, err := os.Stat(moved); err == nil {
+				return moved
+			}
+		}
+	}
+	return path
+}
+
+// PromoteCollection opens the collection and advances its content to the review stage.
+func PromoteCollection(name string, stage string, at time.Time) error {
+	c, err := OpenCollection(name)
+	if err != nil {
+		return err
+	}
+	return c.Promote(stage, at)
+}
+
+// recordCreated records CompletedBy creating the page json files with the uris that have no history yet, writing the
+// collection json if any are recorded.
+func (c *Collection) recordCreated(uris ...string) error {
+	if CompletedBy == "" {
+		return nil
+	}
+
+	created := make([]string, 0, len(uris))
+	for _, uri := range uris {
+		if len(c.EventsByURI[uri]) == 0 {
+			created = append(created, uri)
+		}
+	}
+	if len(created) == 0 {
+		return nil
+	}
+
+	c.addFileEvents(c.newEvent(EventCreated, CompletedBy, Now(), created), created)
+
+	b, err := MarshalCollection(c)
+	if err != nil {
+		return err
+	}
+	if err := writeToFile(c.Metadata.CollectionJSON, b); err != nil {
+		return migration.Error{Message: "failed to write collection json file", OriginalErr: err, Params: log.Data{"path": c.Metadata.CollectionJSON}}
+	}
+	return nil
+}
+
+func (c *Collection) addEvents(eventType string, email string, at time.Time, uris []string) {
+	event := c.newEvent(eventType, email, at, uris)
+	c.Events = append(c.Events, event)
+	c.addFileEvents(event, uris)
+}
+
+func (c *Collection) addFileEvents(event *Event, uris []string) {
+	if c.EventsByURI == nil {
+		c.EventsByURI = make(map[string][]*Event)
+	}
+	for _, uri := range uris {
+		c.EventsByURI[uri] = append(c.EventsByURI[uri], event)
+	}
+}
+
+// newEvent returns the event at the time, or a millisecond after the latest event of the collection or of the files
+// with the uris if that is later - zebedee orders a history by date, so each event must be after the one before.
+func (c *Collection) newEvent(eventType string, email string, at time.Time, uris []string) *Event {
+	history := append([]*Event{}, c.Events...)
+	for _, uri := range uris {
+		history = append(history, c.EventsByURI[uri]...)
+	}
+
+	// dates are recorded to the millisecond
+	at = at.Truncate(time.Millisecond)
+	for _, e := range history {
+		if date, err := time.Parse(publishDateFormat, e.Date); err == nil && !at.After(date) {
+			at = date.Add(time.Millisecond)
+		}
+	}
+	return &Event{Date: FormatCollectionDate(at), Type: eventType, Email: email}
+}
+
+// moveContent moves every file in the src directory to the same path in the dst directory, returning the uris of the
+// page json files moved e.g. /economy/.../data.json. The emptied directories are removed from src, src is kept.
+func moveContent(src string, dst string) ([]string, error) {
+	files := make([]string, 0)
+	dirs := make([]string, 0)
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != src {
+				dirs = append(dirs, path)
+			}
+			return nil
+		}
+		files = append(files, contentURI(src, path))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// every target is checked before anything is moved, so content is not left split between the stages
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(dst, filepath.FromSlash(f))); err == nil {
+			return nil, fmt.Errorf("%s is already in %s", f, dst)
+		}
+	}
+
+	uris := make([]string, 0)
+	for i, f := range files {
+		from, to := filepath.Join(src, filepath.FromSlash(f)), filepath.Join(dst, filepath.FromSlash(f))
+		err := os.MkdirAll(filepath.Dir(to), 0755)
+		if err == nil {
+			err = os.Rename(from, to)
+		}
+		if err != nil {
+			return nil, rollback(src, dst, files[:i], err)
+		}
+
+		if name := filepath.Base(f); name == dataJSON || name == dataJSONWelsh {
+			uris = append(uris, f)
+		}
+	}
+
+	// directories are walked parent first, so they are removed deepest first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Remove(dirs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return uris, nil
+}
+
+// rollback moves the files already moved to dst back to src after the move failed with err, removing the directories
+// it leaves empty in dst. It returns err, or the error of the first file that could not be moved back.
+func rollback(src string, dst string, moved []string, err error) error {
+	root := filepath.Clean(dst)
+	for _, f := range moved {
+		target := filepath.Join(dst, filepath.FromSlash(f))
+		if rbErr := os.Rename(target, filepath.Join(src, filepath.FromSlash(f))); rbErr != nil {
+			return fmt.Errorf("%s, and failed to move %s back to %s: %s", err, f, src, rbErr)
+		}
+
+		// os.Remove fails on the first directory that is not empty
+		for dir := filepath.Dir(target); dir != root && os.Remove(dir) == nil; dir = filepath.Dir(dir) {
+		}
+	}
+	return err
+}